@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+)
+
+const (
+	screenW = 640
+	screenH = 480
+)
+
+// assetChecker checks the themes of an asset pack the way the game
+// would load them, stacked over the built-in assets, and collects
+// every problem instead of stopping at the first.
+type assetChecker struct {
+	fsys     fs.FS
+	theme    string
+	errors   []string
+	warnings []string
+	decoded  map[string]image.Point
+}
+
+func (c *assetChecker) errorf(format string, args ...interface{}) {
+	c.errors = append(c.errors, c.theme+": "+fmt.Sprintf(format, args...))
+}
+
+func (c *assetChecker) warnf(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, c.theme+": "+fmt.Sprintf(format, args...))
+}
+
+func (c *assetChecker) read(what, name string) []byte {
+	if name == "" {
+		c.errorf("%s: not set in the manifest", what)
+		return nil
+	}
+	buf, err := fs.ReadFile(c.fsys, name)
+	if err != nil {
+		c.errorf("%s: %v", what, err)
+		return nil
+	}
+	return buf
+}
+
+// image decodes an image and returns its size, or a zero size if
+// it is missing or broken.
+func (c *assetChecker) image(what, name string) image.Point {
+	if size, found := c.decoded[name]; found {
+		return size
+	}
+
+	var size image.Point
+	if buf := c.read(what, name); buf != nil {
+		m, _, err := image.Decode(bytes.NewReader(buf))
+		if err != nil {
+			c.errorf("%s: %s: %v", what, name, err)
+		} else {
+			size = m.Bounds().Size()
+		}
+	}
+	c.decoded[name] = size
+	return size
+}
+
+// screenImage checks an image covering the whole screen.
+func (c *assetChecker) screenImage(what, name string) {
+	size := c.image(what, name)
+	if size != (image.Point{}) && size != (image.Point{screenW, screenH}) {
+		c.errorf("%s: %s is %dx%d, want %dx%d", what, name, size.X, size.Y, screenW, screenH)
+	}
+}
+
+func (c *assetChecker) font(what, name string) {
+	buf := c.read(what, name)
+	if buf == nil {
+		return
+	}
+	magic := []string{"\x00\x01\x00\x00", "OTTO", "true", "ttcf"}
+	for _, m := range magic {
+		if bytes.HasPrefix(buf, []byte(m)) {
+			return
+		}
+	}
+	c.errorf("%s: %s is not a TrueType or OpenType font", what, name)
+}
+
+// sound checks a sound effect or music. The game plays on without
+// the ones it can't load, so they are only warned about.
+func (c *assetChecker) sound(what, name string, music bool) {
+	buf, err := fs.ReadFile(c.fsys, name)
+	if err != nil {
+		c.warnf("%s: %v", what, err)
+		return
+	}
+
+	wav := len(buf) >= 12 && string(buf[:4]) == "RIFF" && string(buf[8:12]) == "WAVE"
+	if wav {
+		return
+	}
+	if music {
+		for _, m := range []string{"OggS", "fLaC", "ID3", "\xff\xfb", "MThd"} {
+			if bytes.HasPrefix(buf, []byte(m)) {
+				return
+			}
+		}
+	}
+	c.warnf("%s: %s is not a format the mixer can play", what, name)
+}
+
+func (c *assetChecker) check(name string) {
+	c.theme = name
+	buf := c.read("manifest", themeManifest(name))
+	if buf == nil {
+		return
+	}
+	t := &Theme{}
+	if err := json.Unmarshal(buf, t); err != nil {
+		c.errorf("manifest: %v", err)
+		return
+	}
+
+	l := &t.Layout
+	cell := image.Point{l.ReelX[1] - l.ReelX[0], l.ReelY[1] - l.ReelY[0]}
+	for i, sym := range t.Symbols {
+		size := c.image(fmt.Sprint("symbol ", i+1), sym)
+		if size.X > cell.X || size.Y > cell.Y {
+			c.errorf("symbol %d: %s is %dx%d, larger than the %dx%d reel cells", i+1, sym, size.X, size.Y, cell.X, cell.Y)
+		}
+	}
+
+	m := &t.Images
+	c.screenImage("background", m.Background)
+	c.screenImage("window", m.Window)
+	c.screenImage("menu background", m.MenuBackground)
+	c.screenImage("menu overlay", m.MenuOverlay)
+	c.screenImage("intro sun", m.IntroSun)
+	if len(m.MenuSlides) == 0 {
+		c.errorf("menu slides: none listed")
+	}
+	for i, slide := range m.MenuSlides {
+		c.screenImage(fmt.Sprint("menu slide ", i+1), slide)
+	}
+	c.image("reels", m.Reels)
+	c.image("menu high score", m.MenuHighScore)
+	c.image("intro border", m.IntroBorder)
+	c.image("intro point", m.IntroPoint)
+	if size := c.image("menu row", m.MenuRow); size.X != 0 && size.X != screenW {
+		c.errorf("menu row: %s is %d wide, want %d", m.MenuRow, size.X, screenW)
+	}
+
+	c.font("digital font", t.Fonts.Digital)
+	c.font("text font", t.Fonts.Text)
+
+	c.sound("click sound", t.Sounds.Click, false)
+	c.sound("reel sound", t.Sounds.Reel, false)
+	c.sound("beep sound", t.Sounds.Beep, false)
+	c.sound("music", t.Sounds.Music, true)
+}
+
+func checkAssets(name string) int {
+	pack, err := openPack(name)
+	ck(err)
+
+	fsys := Layers{pack, builtinAssets()}
+	c := &assetChecker{
+		fsys:    fsys,
+		decoded: make(map[string]image.Point),
+	}
+	themes := 0
+	for _, dir := range fsys.readDir("themes") {
+		if _, err := fs.Stat(fsys, themeManifest(dir)); err == nil {
+			c.check(dir)
+			themes++
+		}
+	}
+	if themes == 0 {
+		c.theme = name
+		c.errorf("no themes")
+	}
+
+	for _, w := range c.warnings {
+		fmt.Println("warning:", w)
+	}
+	for _, e := range c.errors {
+		fmt.Println("error:", e)
+	}
+	fmt.Printf("%d themes checked, %d errors, %d warnings\n", themes, len(c.errors), len(c.warnings))
+	return len(c.errors)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"archive/zip"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/qeedquan/go-media/sdl"
+)
+
+// Assets are read through a stack of filesystems: the -assets
+// directory or .bfpack zip archive first, then the assets built into
+// the binary. A file in a pack takes the place of the built-in one
+// with the same name, so a mod or theme only brings what it changes.
+
+//go:embed assets
+var embedded embed.FS
+
+// Layers is a stack of asset filesystems, the first one having a
+// file wins.
+type Layers []fs.FS
+
+var (
+	vfs Layers
+
+	// Fonts and music are streamed from their RWops for as long as
+	// they are open, so the memory they are read from is kept here.
+	pinned = make(map[string][]byte)
+)
+
+func builtinAssets() fs.FS {
+	sub, err := fs.Sub(embedded, "assets")
+	ck(err)
+	return sub
+}
+
+// openPack opens an asset directory or a zip archive of one.
+func openPack(name string) (fs.FS, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return os.DirFS(name), nil
+	}
+
+	z, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return z, nil
+}
+
+// initAssets stacks the -assets pack over the built-in assets. The
+// default assets directory next to the binary is optional, one given
+// in the configuration is not.
+func initAssets() {
+	vfs = Layers{builtinAssets()}
+
+	pack, err := openPack(conf.assets)
+	if os.IsNotExist(err) && !configured("assets") {
+		return
+	}
+	ck(err)
+	vfs = append(Layers{pack}, vfs...)
+}
+
+func (l Layers) Open(name string) (fs.File, error) {
+	err := error(&fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist})
+	for _, f := range l {
+		file, xerr := f.Open(name)
+		if xerr == nil {
+			return file, nil
+		}
+		if !errors.Is(xerr, fs.ErrNotExist) {
+			err = xerr
+		}
+	}
+	return nil, err
+}
+
+// readDir lists a directory of every layer.
+func (l Layers) readDir(name string) []string {
+	seen := make(map[string]bool)
+	for _, f := range l {
+		dirs, _ := fs.ReadDir(f, name)
+		for _, d := range dirs {
+			seen[d.Name()] = true
+		}
+	}
+
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readAsset(name string) ([]byte, error) {
+	return fs.ReadFile(vfs, name)
+}
+
+// openAsset returns an RWops reading the named asset from memory.
+// If keep is set the memory stays around for a font or music to
+// stream from.
+func openAsset(name string, keep bool) (*sdl.RWops, error) {
+	buf, err := readAsset(name)
+	if err != nil {
+		return nil, err
+	}
+	if keep {
+		pinned[name] = buf
+	}
+	return sdl.RWFromMem(buf)
+}
+
+// extractAssets writes the embedded assets under dir. Files already
+// there are left alone, so extracting again doesn't undo a mod.
+func extractAssets(dir string) error {
+	builtin := builtinAssets()
+	return fs.WalkDir(builtin, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		out := filepath.Join(dir, filepath.FromSlash(name))
+		if d.IsDir() {
+			return os.MkdirAll(out, 0755)
+		}
+		if exists(out) {
+			fmt.Println("kept", out)
+			return nil
+		}
+
+		buf, err := fs.ReadFile(builtin, name)
+		if err != nil {
+			return err
+		}
+		fmt.Println("wrote", out)
+		return ioutil.WriteFile(out, buf, 0644)
+	})
+}
+
+func assetsCommand(args []string) {
+	switch {
+	case len(args) == 1 && args[0] == "extract":
+		ck(extractAssets("assets"))
+	case len(args) == 2 && args[0] == "extract":
+		ck(extractAssets(args[1]))
+	case len(args) == 2 && args[0] == "check":
+		if checkAssets(args[1]) > 0 {
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "usage: bfruit assets extract [dir]")
+		fmt.Fprintln(os.Stderr, "       bfruit assets check <dir|bfpack>")
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	maxAuditSize = 1 << 20
+	auditKeep    = 9
+)
+
+// How a spin interrupted by a crash was recovered on the next start.
+const (
+	auditSettled  = "settled"
+	auditRefunded = "refunded"
+)
+
+// AuditEntry is a line of the audit log, one is written for
+// every spin with everything needed to replay it. A refunded spin
+// was never drawn, it only returns the bet.
+type AuditEntry struct {
+	Time      time.Time
+	Session   string
+	Profile   string
+	Slot      int
+	Paytable  string
+	Draws     [9]int
+	Show      [9]int
+	Lines     [5]int
+	Bet       int
+	Cost      int
+	Payout    int
+	Before    int
+	After     int
+	Recovered string
+}
+
+func auditName() string {
+	return filepath.Join(conf.pref, "audit.log")
+}
+
+func newSessionID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func audit(e *AuditEntry) error {
+	name := auditName()
+	if fi, err := os.Stat(name); err == nil && fi.Size() >= maxAuditSize {
+		rotateAudit(name)
+	}
+
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(buf, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if xerr := f.Close(); err == nil {
+		err = xerr
+	}
+	return err
+}
+
+// rotateAudit shifts audit.log to audit.log.1, audit.log.1 to
+// audit.log.2 and so on, dropping the oldest one.
+func rotateAudit(name string) {
+	for i := auditKeep; i > 0; i-- {
+		old := name
+		if i > 1 {
+			old = fmt.Sprint(name, ".", i-1)
+		}
+		if exists(old) {
+			os.Rename(old, fmt.Sprint(name, ".", i))
+		}
+	}
+}
+
+// auditFiles returns the audit log and its rotated copies,
+// oldest first. There are none before the first spin.
+func auditFiles() []string {
+	var names []string
+	name := auditName()
+	for i := auditKeep; i > 0; i-- {
+		if old := fmt.Sprint(name, ".", i); exists(old) {
+			names = append(names, old)
+		}
+	}
+	if exists(name) {
+		names = append(names, name)
+	}
+	return names
+}
+
+// verifyAudit replays every entry of the audit logs through the
+// engine and reports the entries that do not add up.
+func verifyAudit(names []string) int {
+	bad := 0
+	entries := 0
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			bad++
+			continue
+		}
+
+		s := bufio.NewScanner(f)
+		for line := 1; s.Scan(); line++ {
+			var e AuditEntry
+			entries++
+			if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+				fmt.Printf("%s:%d: %v\n", name, line, err)
+				bad++
+				continue
+			}
+			for _, p := range e.verify() {
+				fmt.Printf("%s:%d: session %s: %s\n", name, line, e.Session, p)
+				bad++
+			}
+		}
+		if err := s.Err(); err != nil {
+			fmt.Printf("%s: %v\n", name, err)
+			bad++
+		}
+		f.Close()
+	}
+
+	fmt.Printf("%d entries checked, %d problems\n", entries, bad)
+	return bad
+}
+
+func (e *AuditEntry) verify() []string {
+	var p []string
+
+	if e.Recovered == auditRefunded {
+		if e.After != e.Before {
+			p = append(p, fmt.Sprintf("refund changed credit from %d to %d", e.Before, e.After))
+		}
+		return p
+	}
+
+	pt := findPaytable(e.Paytable)
+	if pt.Name != e.Paytable {
+		p = append(p, fmt.Sprintf("unknown paytable %q", e.Paytable))
+	}
+
+	var show [9]int
+	for i, r := range e.Draws {
+		show[i] = pt.symbol(r)
+	}
+	if show != e.Show {
+		p = append(p, fmt.Sprintf("grid %v does not match draws, engine says %v", e.Show, show))
+	}
+
+	wins := lines(e.Show)
+	if wins != e.Lines {
+		p = append(p, fmt.Sprintf("winning lines %v, engine says %v", e.Lines, wins))
+	}
+
+	pay := payout(wins, e.Bet)
+	if pay != e.Payout {
+		p = append(p, fmt.Sprintf("payout %d, engine says %d", e.Payout, pay))
+	}
+
+	if e.Cost != e.Bet && e.Cost != 0 {
+		p = append(p, fmt.Sprintf("cost %d does not match bet %d", e.Cost, e.Bet))
+	}
+
+	after := settle(e.Before-e.Cost, pay)
+	if after != e.After {
+		p = append(p, fmt.Sprintf("credit after %d, engine says %d", e.After, after))
+	}
+	return p
+}
+
+func auditCommand(args []string) {
+	if len(args) < 1 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, "usage: bfruit audit verify [file ...]")
+		os.Exit(2)
+	}
+
+	names := args[1:]
+	if len(names) == 0 {
+		names = auditFiles()
+	}
+	if verifyAudit(names) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/qeedquan/go-media/sdl"
+	"github.com/qeedquan/go-media/sdl/sdlgfx"
+	"github.com/qeedquan/go-media/sdl/sdlttf"
+)
+
+// A win is celebrated by how many times the bet it pays: small wins
+// flash the winning cells, big wins count the win up under a banner
+// and mega wins shower coins over it too. It all runs off the time
+// since the win, so it looks the same at any frame rate, and a key
+// or a tap skips to the end.
+const (
+	winSmall = iota
+	winBig
+	winMega
+)
+
+// Wins paying at least these many times the bet are big and mega.
+const (
+	bigWin  = 8
+	megaWin = 20
+
+	flashPeriod = 250 * time.Millisecond
+	coinRate    = 40  // coins a second
+	gravity     = 600 // pixels a second squared
+	coinSize    = 7
+)
+
+type fallingCoin struct {
+	x, y   float64
+	vx, vy float64
+}
+
+type Celebration struct {
+	tier   int
+	amount int
+	cells  [9]bool
+	start  time.Time
+	last   time.Time
+	count  time.Duration
+	hold   time.Duration
+	coins  []fallingCoin
+	spawn  float64
+}
+
+// newCelebration returns the celebration for the lines won, or nil
+// if nothing was won.
+func newCelebration(wins [5]int, amount, bet int) *Celebration {
+	if amount <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	c := &Celebration{
+		amount: amount,
+		start:  now,
+		last:   now,
+		hold:   1500 * time.Millisecond,
+	}
+	for i, n := range wins {
+		if n != 0 {
+			for _, k := range paylines[i] {
+				c.cells[k] = true
+			}
+		}
+	}
+	switch {
+	case amount >= megaWin*bet:
+		c.tier = winMega
+		c.count = 5 * time.Second
+		c.hold = 2 * time.Second
+	case amount >= bigWin*bet:
+		c.tier = winBig
+		c.count = 2500 * time.Millisecond
+	}
+	return c
+}
+
+func (c *Celebration) elapsed() time.Duration {
+	return time.Since(c.start)
+}
+
+func (c *Celebration) done() bool {
+	return c.elapsed() >= c.count+c.hold
+}
+
+// skip jumps to the end, the win counted and the coins gone.
+func (c *Celebration) skip() {
+	c.start = time.Now().Add(-c.count - c.hold)
+	c.coins = nil
+}
+
+// counted is how much of the win the meters show so far.
+func (c *Celebration) counted() int {
+	t := c.elapsed()
+	if t >= c.count {
+		return c.amount
+	}
+	return int(int64(c.amount) * int64(t) / int64(c.count))
+}
+
+func (c *Celebration) flash() bool {
+	return c.elapsed()/flashPeriod%2 == 0
+}
+
+// update moves the coins on by the time since the last frame. They
+// rain while the win counts up and fall off the screen after.
+func (c *Celebration) update() {
+	now := time.Now()
+	dt := now.Sub(c.last).Seconds()
+	c.last = now
+	if c.tier != winMega {
+		return
+	}
+
+	if c.elapsed() < c.count {
+		for c.spawn += dt * coinRate; c.spawn >= 1; c.spawn-- {
+			c.coins = append(c.coins, fallingCoin{
+				x:  rand.Float64() * logicalW,
+				y:  -coinSize,
+				vx: rand.Float64()*80 - 40,
+				vy: rand.Float64() * 100,
+			})
+		}
+	}
+
+	coins := c.coins[:0]
+	for _, p := range c.coins {
+		p.vy += gravity * dt
+		p.x += p.vx * dt
+		p.y += p.vy * dt
+		if p.y < logicalH+coinSize {
+			coins = append(coins, p)
+		}
+	}
+	c.coins = coins
+}
+
+// drawCells flashes the winning cells over the symbols.
+func (c *Celebration) drawCells() {
+	if !c.flash() || c.done() {
+		return
+	}
+
+	l := &theme.Layout
+	w, h := l.ReelX[1]-l.ReelX[0], l.ReelY[1]-l.ReelY[0]
+	col := theme.Colors.Lines.Color
+	col.A = 96
+	for i, won := range c.cells {
+		if won {
+			x, y := l.ReelX[i/3], l.ReelY[i%3]
+			sdlgfx.Box(screen.Renderer, x, y, x+w-1, y+h-1, col)
+		}
+	}
+}
+
+// drawBanner draws the banner and coins of a big or mega win.
+func (c *Celebration) drawBanner(g *Game) {
+	if c.tier == winSmall || c.done() {
+		return
+	}
+
+	colors := &theme.Colors
+	l := &theme.Layout
+	w, h := l.ReelX[1]-l.ReelX[0], l.ReelY[1]-l.ReelY[0]
+	x0, x1 := l.ReelX[0], l.ReelX[2]+w
+	x, y := (x0+x1)/2, (l.ReelY[0]+l.ReelY[2]+h)/2
+	sdlgfx.ThickLine(screen.Renderer, x0, y, x1, y, 130, colors.Panel.Color)
+
+	title := tr("BIG WIN")
+	if c.tier == winMega {
+		title = tr("MEGA WIN")
+	}
+	tc := colors.PanelText.Color
+	if c.flash() {
+		tc = colors.Lines.Color
+	}
+	blitCentered(g.creditFont, x, y-60, tc, title)
+	blitCentered(g.digiFont, x, y+20, colors.Digits.Color, num(c.counted()))
+
+	for _, p := range c.coins {
+		sdlgfx.FilledCircle(screen.Renderer, int(p.x), int(p.y), coinSize, colors.Lines.Color)
+	}
+}
+
+func blitCentered(font *sdlttf.Font, x, y int, c sdl.Color, text string) {
+	w, _, err := font.SizeUTF8(text)
+	ck(err)
+	blitText(font, x-w/2, y, c, text)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Every option is a flag, and its value is layered: the default,
+// then the config file in the pref directory, then the settings the
+// player changed in their profile, then a BFRUIT_<OPTION> environment
+// variable, then the command line. The config file is plain JSON
+// meant to be edited by hand, so unlike the other files in the pref
+// directory it has no header.
+const configName = "config.json"
+
+// Where the value of an option came from.
+const (
+	fromDefault = "default"
+	fromFile    = "config file"
+	fromProfile = "profile"
+	fromEnv     = "environment"
+	fromFlag    = "command line"
+)
+
+// settingOptions are the options the player changes in the Settings
+// menu, kept in their profile.
+var settingOptions = []string{
+	"fullscreen",
+	"music",
+	"sound",
+	"volume",
+	"musicvolume",
+	"effectsvolume",
+	"mute",
+	"theme",
+	"lang",
+	"speed",
+}
+
+// option is the value of an option and where it came from.
+type option struct {
+	value  string
+	source string
+}
+
+var (
+	sources = make(map[string]string)
+
+	// fileSettings are the setting options as the layers under the
+	// profile left them, for the settings of a profile to go over.
+	fileSettings = make(map[string]option)
+)
+
+func configPath() string {
+	return filepath.Join(conf.pref, configName)
+}
+
+func envName(option string) string {
+	return "BFRUIT_" + strings.ToUpper(option)
+}
+
+// configured reports whether an option was given at all, rather than
+// left at its default.
+func configured(name string) bool {
+	return sources[name] != fromDefault
+}
+
+// overridden reports whether an option was given in the environment
+// or on the command line, which win over the profile and last for
+// the run only.
+func overridden(name string) bool {
+	return sources[name] == fromEnv || sources[name] == fromFlag
+}
+
+func setOption(name, value, source, where string) {
+	if err := flag.Set(name, value); err != nil {
+		log.SetPrefix("config: ")
+		log.Printf("%s: %v", where, err)
+		return
+	}
+	sources[name] = source
+}
+
+// loadConfig layers the config file and the environment under the
+// flags given on the command line. The config file is found through
+// the pref option, so that one can't be set in it.
+func loadConfig() {
+	flag.VisitAll(func(f *flag.Flag) {
+		sources[f.Name] = fromDefault
+	})
+	flag.Visit(func(f *flag.Flag) {
+		sources[f.Name] = fromFlag
+	})
+
+	env := func(name string) {
+		value, found := os.LookupEnv(envName(name))
+		if found && sources[name] != fromFlag {
+			setOption(name, value, fromEnv, envName(name))
+		}
+	}
+	env("pref")
+
+	file, err := readConfig()
+	if err != nil && !os.IsNotExist(err) {
+		log.SetPrefix("config: ")
+		log.Print(err)
+	}
+	var names []string
+	for name := range file {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch {
+		case name == "pref" || flag.Lookup(name) == nil:
+			log.SetPrefix("config: ")
+			log.Printf("%s: unknown option %q", configPath(), name)
+		case sources[name] == fromDefault:
+			setOption(name, fmt.Sprint(file[name]), fromFile, configPath())
+		}
+	}
+
+	flag.VisitAll(func(f *flag.Flag) {
+		if f.Name != "pref" {
+			env(f.Name)
+		}
+	})
+
+	for _, name := range settingOptions {
+		fileSettings[name] = option{flag.Lookup(name).Value.String(), sources[name]}
+	}
+}
+
+func readConfig() (map[string]interface{}, error) {
+	buf, err := ioutil.ReadFile(configPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var file map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %v", configPath(), err)
+	}
+	return file, nil
+}
+
+// configCommand prints the value of every option and where it came
+// from, with the settings of the active profile applied the way the
+// game would.
+func configCommand(args []string) {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: bfruit config")
+		os.Exit(2)
+	}
+
+	name := activeProfile()
+	p := &Profile{
+		name: name,
+		dir:  filepath.Join(profilesDir(), name),
+	}
+	p.loadSettings()
+
+	fmt.Println("config file:", configPath())
+	flag.VisitAll(func(f *flag.Flag) {
+		value, source := f.Value.String(), sources[f.Name]
+		if v, found := p.settings[f.Name]; found && !overridden(f.Name) {
+			value, source = v, fromProfile+" "+name
+		}
+		if source == fromEnv {
+			source += " " + envName(f.Name)
+		}
+		fmt.Printf("%-14s %-24q %s\n", f.Name, value, source)
+	})
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/qeedquan/go-media/sdl"
+	"github.com/qeedquan/go-media/sdl/sdlimage/sdlcolor"
+)
+
+const controlsVersion = 2
+
+// Action is something the player does, bound to one or more keys.
+type Action int
+
+const (
+	actSpin Action = iota
+	actBetUp
+	actBetDown
+	actHelp
+	actEndGame
+	actBack
+	actConfirm
+	actMute
+	numActions
+)
+
+// Where an action is used. Actions used in the same place can't
+// share a key.
+const (
+	inGame = 1 << iota
+	inMenu
+)
+
+var actions = [numActions]struct {
+	name  string
+	where int
+	keys  []sdl.Keycode
+}{
+	actSpin:    {"Spin", inGame, []sdl.Keycode{sdl.K_LEFT, sdl.K_RIGHT, padA}},
+	actBetUp:   {"Raise bet", inGame, []sdl.Keycode{sdl.K_UP}},
+	actBetDown: {"Lower bet", inGame, []sdl.Keycode{sdl.K_DOWN}},
+	actHelp:    {"Help", inGame, []sdl.Keycode{sdl.K_F1, padStart}},
+	actEndGame: {"End game", inGame, []sdl.Keycode{sdl.K_RETURN, padBack}},
+	actBack:    {"Back", inGame | inMenu, []sdl.Keycode{sdl.K_ESCAPE, padB}},
+	actConfirm: {"Confirm", inMenu, []sdl.Keycode{sdl.K_RETURN, sdl.K_SPACE, padA}},
+	actMute:    {"Mute", inGame | inMenu, []sdl.Keycode{sdl.K_m}},
+}
+
+// Bindings are the keys of every action.
+type Bindings [numActions][]sdl.Keycode
+
+var (
+	bindings = defaultBindings()
+)
+
+func defaultBindings() Bindings {
+	var b Bindings
+	for a := range actions {
+		b[a] = actions[a].keys
+	}
+	return b
+}
+
+func (a Action) String() string {
+	return tr(actions[a].name)
+}
+
+// bound reports whether the key is bound to the action.
+func bound(sym sdl.Keycode, a Action) bool {
+	for _, k := range bindings[a] {
+		if k == sym {
+			return true
+		}
+	}
+	return false
+}
+
+// keyNames lists the keys bound to an action.
+func keyNames(a Action) string {
+	var names []string
+	for _, k := range bindings[a] {
+		names = append(names, keyName(k))
+	}
+	return strings.Join(names, ", ")
+}
+
+// fixedKeys are the keys that can't be rebound where an action is
+// used: the arrows move through the menus and adjust the settings,
+// and T takes a ticket in the game, as does the coin key on a kiosk.
+func fixedKeys(where int) []sdl.Keycode {
+	var keys []sdl.Keycode
+	if where&inMenu != 0 {
+		keys = append(keys, sdl.K_LEFT, sdl.K_RIGHT, sdl.K_UP, sdl.K_DOWN)
+	}
+	if where&inGame != 0 {
+		keys = append(keys, sdl.K_t)
+		if conf.kiosk {
+			keys = append(keys, coinKey)
+		}
+	}
+	return keys
+}
+
+// conflict returns why the key can't be bound to the action, or
+// an empty string if it can.
+func (b *Bindings) conflict(a Action, sym sdl.Keycode) string {
+	where := actions[a].where
+	for _, k := range fixedKeys(where) {
+		if k == sym {
+			return trf("%s is reserved", keyName(sym))
+		}
+	}
+	for other := range b {
+		if Action(other) == a || actions[other].where&where == 0 {
+			continue
+		}
+		for _, k := range b[other] {
+			if k == sym {
+				return trf("%s is already bound to %s", keyName(sym), Action(other))
+			}
+		}
+	}
+	return ""
+}
+
+func (b *Bindings) valid() bool {
+	for a := range b {
+		if len(b[a]) == 0 {
+			return false
+		}
+		for _, k := range b[a] {
+			if k == sdl.K_UNKNOWN || b.conflict(Action(a), k) != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func controlsName() string {
+	return filepath.Join(conf.pref, "controls")
+}
+
+// loadControls reads the key bindings, which are kept by key name
+// so the file stays readable.
+func loadControls() {
+	var names map[string][]string
+	version, err := loadData(controlsName(), "controls", controlsVersion, &names)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			notify("controls: %v", err)
+		}
+		return
+	}
+
+	b := defaultBindings()
+	for a := range actions {
+		keys, found := names[actions[a].name]
+		if !found {
+			continue
+		}
+		b[a] = nil
+		pad := false
+		for _, name := range keys {
+			k := keyFromName(name)
+			b[a] = append(b[a], k)
+			pad = pad || isPadKey(k)
+		}
+
+		// rebinding a key of version 1 dropped the pad buttons,
+		// those actions get the default ones back
+		if version < 2 && !pad {
+			for _, k := range actions[a].keys {
+				if isPadKey(k) {
+					b[a] = append(b[a], k)
+				}
+			}
+		}
+	}
+	if !b.valid() {
+		notify("The controls conflict, using the default ones")
+		return
+	}
+	bindings = b
+}
+
+func saveControls() {
+	names := make(map[string][]string)
+	for a := range actions {
+		for _, k := range bindings[a] {
+			names[actions[a].name] = append(names[actions[a].name], keyName(k))
+		}
+	}
+	err := saveData(controlsName(), "controls", controlsVersion, names)
+	if err != nil {
+		notify("saving controls: %v", err)
+	}
+}
+
+type controlsSelector struct{}
+
+func (controlsSelector) Choices() []string {
+	var choices []string
+	for a := Action(0); a < numActions; a++ {
+		choices = append(choices, choice("%s: %s", a, keyNames(a)))
+	}
+	return append(choices,
+		choice("Defaults"),
+		choice("Exit"),
+	)
+}
+
+func (controlsSelector) Select(n int) bool {
+	switch a := Action(n); {
+	case a < numActions:
+		state = newKeyCapture(a).Run
+		return true
+	case a == numActions:
+		bindings = defaultBindings()
+		saveControls()
+		controls.Refresh()
+		return false
+	default:
+		state = settings.Run
+		return true
+	}
+}
+
+// KeyCapture is the scene binding the next key or button pressed to
+// an action. Escape or B cancels, unless it is Back being bound.
+type KeyCapture struct {
+	action Action
+	err    string
+}
+
+func newKeyCapture(a Action) *KeyCapture {
+	return &KeyCapture{action: a}
+}
+
+func (c *KeyCapture) Run() {
+	for {
+		if c.event() {
+			return
+		}
+		c.draw()
+		sdl.Delay(1000 / 60)
+	}
+}
+
+func (c *KeyCapture) event() bool {
+	for {
+		ev := pollEvent()
+		if ev == nil {
+			break
+		}
+		switch ev := ev.(type) {
+		case sdl.QuitEvent:
+			quit()
+		case sdl.KeyDownEvent:
+			playSound(menu.bsound)
+			if (ev.Sym == sdl.K_ESCAPE || ev.Sym == padB) && c.action != actBack {
+				state = controls.Run
+				return true
+			}
+			if c.err = bindings.conflict(c.action, ev.Sym); c.err != "" {
+				break
+			}
+			// a key replaces the keys of its own device, the keyboard
+			// or the pad, and leaves the other one bound
+			keys := []sdl.Keycode{ev.Sym}
+			for _, k := range bindings[c.action] {
+				if isPadKey(k) != isPadKey(ev.Sym) {
+					keys = append(keys, k)
+				}
+			}
+			bindings[c.action] = keys
+			saveControls()
+			state = controls.Run
+			return true
+		}
+	}
+	return false
+}
+
+func (c *KeyCapture) draw() {
+	m := menu
+
+	screen.SetDrawColor(sdlcolor.Black)
+	screen.Clear()
+
+	m.drawSlide()
+	m.background.Blit(0, 0)
+	m.sav.Blit(0, 60)
+	m.sav.Blit(0, 120)
+
+	blitText(m.font, 50, 75, theme.Colors.Text.Color, trf("Press a key for %s", c.action))
+	blitText(m.smallFont, 50, 140, theme.Colors.Text.Color, trf("Bound to: %s", keyNames(c.action)))
+	if c.action != actBack {
+		blitText(m.smallFont, 50, 160, theme.Colors.Text.Color, tr("Escape or B cancels"))
+	}
+	if c.err != "" {
+		blitText(m.smallFont, 50, 190, sdlcolor.Red, c.err)
+	}
+
+	m.tick()
+	drawNotices()
+	screen.Present()
+}
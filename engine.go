@@ -0,0 +1,115 @@
+package main
+
+// The rules of the machine, kept apart from the drawing code so
+// that a spin can be settled or replayed without a screen.
+
+// paylines are the cells of the show grid making up each winning
+// line. The grid is stored column by column, top to bottom.
+var paylines = [5][3]int{
+	{0, 3, 6},
+	{1, 4, 7},
+	{2, 5, 8},
+	{0, 4, 8},
+	{2, 4, 6},
+}
+
+// numSymbols is how many symbols there are, numbered from 1 to
+// numSymbols. A line of the last one is the jackpot.
+const numSymbols = 8
+
+// Paytable sets how often every symbol comes up on a cell, which
+// is what decides the payout percentage of the machine.
+type Paytable struct {
+	Name    string
+	Weights [numSymbols]int
+}
+
+// paytables are the payout profiles the operator can choose from,
+// the first one is the weighting of the original game.
+var paytables = []Paytable{
+	{"standard", [numSymbols]int{81, 73, 60, 70, 20, 15, 10, 5}},
+	{"loose", [numSymbols]int{136, 73, 60, 70, 20, 15, 10, 5}},
+	{"generous", [numSymbols]int{174, 73, 60, 70, 20, 15, 10, 5}},
+}
+
+func findPaytable(name string) *Paytable {
+	for i := range paytables {
+		if paytables[i].Name == name {
+			return &paytables[i]
+		}
+	}
+	return &paytables[0]
+}
+
+// total is the range of a draw, draws go from 1 to total.
+func (p *Paytable) total() int {
+	n := 0
+	for _, w := range p.Weights {
+		n += w
+	}
+	return n
+}
+
+// symbol maps a draw to the symbol on a cell, the rarest
+// symbols take the lowest draws.
+func (p *Paytable) symbol(r int) int {
+	lo := 1
+	for n := len(p.Weights); n > 0; n-- {
+		hi := lo + p.Weights[n-1]
+		if lo <= r && r < hi {
+			return n
+		}
+		lo = hi
+	}
+	return 0
+}
+
+// rtp is the expected share of the bets paid back.
+func (p *Paytable) rtp() float64 {
+	t := float64(p.total())
+	r := 0.0
+	for i, w := range p.Weights {
+		q := float64(w) / t
+		r += q * q * q * float64(linePay(i+1, 1))
+	}
+	return r * float64(len(paylines))
+}
+
+// lines returns the symbol of every winning line, zero for the
+// lines that did not win.
+func lines(show [9]int) [5]int {
+	var wins [5]int
+	for i, l := range paylines {
+		if show[l[0]] == show[l[1]] && show[l[1]] == show[l[2]] {
+			wins[i] = show[l[0]]
+		}
+	}
+	return wins
+}
+
+// linePay is what a single winning line pays for a bet.
+func linePay(n, bet int) int {
+	if n <= 0 {
+		return 0
+	}
+	return bet*n + bet
+}
+
+// payout is the sum paid for all winning lines of a spin.
+func payout(wins [5]int, bet int) int {
+	total := 0
+	for _, n := range wins {
+		total += linePay(n, bet)
+	}
+	return total
+}
+
+// settle returns the credit after a spin paid out, capped
+// at what the credit meter can show.
+func settle(credit, pay int) int {
+	credit += pay
+	if credit > maxScore {
+		credit = maxScore
+	}
+	return credit
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/qeedquan/go-media/sdl"
+	"github.com/qeedquan/go-media/sdl/sdlgfx"
+	"github.com/qeedquan/go-media/sdl/sdlimage/sdlcolor"
+)
+
+const initials = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 ."
+
+// Entry is a scene asking the player to type in a line of text.
+// In arcade mode the text has a fixed length and every letter is
+// picked with the arrow keys instead of being typed.
+type Entry struct {
+	prompt string
+	text   []rune
+	max    int
+	err    string
+	done   func(string) error
+	next   func()
+	back   func()
+	mask   bool
+
+	arcade bool
+	pos    int
+}
+
+func newEntry(prompt string, max int, done func(string) error, next func()) *Entry {
+	return &Entry{
+		prompt: prompt,
+		max:    max,
+		done:   done,
+		next:   next,
+		back:   next,
+	}
+}
+
+func newInitials(prompt, text string, done func(string) error, next func()) *Entry {
+	e := newEntry(prompt, 3, done, next)
+	e.arcade = true
+	for _, r := range strings.ToUpper(text) {
+		if len(e.text) < e.max && strings.ContainsRune(initials, r) {
+			e.text = append(e.text, r)
+		}
+	}
+	for len(e.text) < e.max {
+		e.text = append(e.text, 'A')
+	}
+	return e
+}
+
+func (e *Entry) Run() {
+	for {
+		if e.event() {
+			return
+		}
+		e.draw()
+		sdl.Delay(1000 / 60)
+	}
+}
+
+func (e *Entry) event() bool {
+	for {
+		ev := pollEvent()
+		if ev == nil {
+			break
+		}
+		switch ev := ev.(type) {
+		case sdl.QuitEvent:
+			quit()
+		case sdl.KeyDownEvent:
+			playSound(menu.bsound)
+			switch sym := ev.Sym; {
+			case sym == sdl.K_ESCAPE, sym == padB:
+				state = e.back
+				return true
+			case sym == sdl.K_RETURN, sym == padA:
+				if e.confirm() {
+					return true
+				}
+			case e.arcade:
+				e.pick(sym)
+			case sym == sdl.K_BACKSPACE:
+				if len(e.text) > 0 {
+					e.text = e.text[:len(e.text)-1]
+				}
+			case len(e.text) >= e.max:
+			case sdl.K_a <= sym && sym <= sdl.K_z:
+				r := rune(sym)
+				if ev.Mod&sdl.KMOD_SHIFT != 0 {
+					r += 'A' - 'a'
+				}
+				e.text = append(e.text, r)
+			case sdl.K_0 <= sym && sym <= sdl.K_9, sym == sdl.K_SPACE:
+				e.text = append(e.text, rune(sym))
+			}
+		case Click:
+			// Letters are typed on a keyboard, but the arcade
+			// initials are picked with the arrows and can be
+			// confirmed with a tap.
+			if e.arcade {
+				playSound(menu.bsound)
+				if e.confirm() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// confirm hands the text over, or keeps the entry open showing why
+// it was refused.
+func (e *Entry) confirm() bool {
+	text := string(e.text)
+	if e.arcade {
+		text = strings.TrimSpace(text)
+	}
+	if err := e.done(text); err != nil {
+		e.err = err.Error()
+		return false
+	}
+	state = e.next
+	return true
+}
+
+func (e *Entry) pick(sym sdl.Keycode) {
+	switch sym {
+	case sdl.K_LEFT:
+		if e.pos--; e.pos < 0 {
+			e.pos = len(e.text) - 1
+		}
+	case sdl.K_RIGHT:
+		if e.pos++; e.pos >= len(e.text) {
+			e.pos = 0
+		}
+	case sdl.K_UP, sdl.K_DOWN:
+		n := len(initials)
+		i := strings.IndexRune(initials, e.text[e.pos])
+		if sym == sdl.K_UP {
+			i = (i + 1) % n
+		} else {
+			i = (i + n - 1) % n
+		}
+		e.text[e.pos] = rune(initials[i])
+	}
+}
+
+func (e *Entry) draw() {
+	m := menu
+
+	screen.SetDrawColor(sdlcolor.Black)
+	screen.Clear()
+
+	m.drawSlide()
+	m.background.Blit(0, 0)
+	m.sav.Blit(0, 60)
+	m.sav.Blit(0, 120)
+
+	blitText(m.font, 50, 75, theme.Colors.Text.Color, tr(e.prompt))
+	if e.arcade {
+		for i, r := range e.text {
+			x := 50 + i*40
+			blitText(m.font, x, 135, theme.Colors.Text.Color, string(r))
+			if i == e.pos {
+				sdlgfx.ThickLine(screen.Renderer, x, 168, x+25, 168, 3, theme.Colors.Text.Color)
+			}
+		}
+		blitText(m.smallFont, 250, 140, theme.Colors.Text.Color, tr("Up/Down: letter  Left/Right: move  Enter: done"))
+	} else {
+		text := string(e.text)
+		if e.mask {
+			text = strings.Repeat("*", len(e.text))
+		}
+		blitText(m.font, 50, 135, theme.Colors.Text.Color, text+"_")
+	}
+	if e.err != "" {
+		blitText(m.smallFont, 50, 190, sdlcolor.Red, e.err)
+	}
+
+	m.tick()
+	drawNotices()
+	screen.Present()
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/qeedquan/go-media/sdl"
 	"github.com/qeedquan/go-media/sdl/sdlgfx"
@@ -15,6 +17,17 @@ const (
 	maxScore = 999999
 )
 
+// How long each winning line is shown for.
+const linePeriod = 1500 * time.Millisecond
+
+// What the player asked for while the reels were spinning.
+// The spin is always landed and paid before acting on it.
+const (
+	stopNone = iota
+	stopMenu
+	stopExit
+)
+
 type Game struct {
 	bsound    *sdlmixer.Chunk
 	rsound    *sdlmixer.Chunk
@@ -24,12 +37,14 @@ type Game struct {
 	background  *Image
 	rlayer      *Image
 	windowLayer *Image
-	images      [8]*Image
+	images      [numSymbols]*Image
 
 	digiFont   *sdlttf.Font
 	creditFont *sdlttf.Font
 	font       *sdlttf.Font
 
+	buttons []Button
+
 	menu    string
 	wins    [5]int
 	show    [9]int
@@ -39,53 +54,150 @@ type Game struct {
 	lastwin int
 	credit  int
 	bet     int
+	spins   int
+	slot    int
+	over    bool
+	stop    int
+	cashed  int
+	voucher *Voucher
+
+	celebration *Celebration
+	presented   time.Time
+	linebet     int
 }
 
 func newGame() *Game {
-	g := &Game{
-		bsound:    loadSound("sounds/CLICK10A.WAV"),
-		rsound:    loadSound("sounds/film_projector.wav"),
-		beepsound: loadSound("sounds/beep.wav"),
-		bgsound:   loadMusic("sounds/background001.wav"),
+	g := &Game{}
+	g.loadTheme()
+	return g
+}
 
-		digiFont:   loadFont("DIGITAL2.ttf", 24),
-		font:       loadFont("LiberationSans-Regular.ttf", 15),
-		creditFont: loadFont("LiberationSans-Regular.ttf", 55),
+func (g *Game) loadTheme() {
+	t := theme
+	g.bsound = loadSound(t.Sounds.Click)
+	g.rsound = loadSound(t.Sounds.Reel)
+	g.beepsound = loadSound(t.Sounds.Beep)
+	g.bgsound = loadMusic(t.Sounds.Music)
 
-		background:  loadImage("img/bg.png"),
-		rlayer:      loadImage("img/rlayer.png"),
-		windowLayer: loadImage("img/windowlayer.png"),
-	}
+	g.digiFont = loadFont(t.Fonts.Digital, 24)
+	g.font = loadFont(t.Fonts.Text, 15)
+	g.creditFont = loadFont(t.Fonts.Text, 55)
+
+	g.background = loadImage(t.Images.Background)
+	g.rlayer = loadImage(t.Images.Reels)
+	g.windowLayer = loadImage(t.Images.Window)
 
 	for i := range g.images {
-		g.images[i] = loadImage(fmt.Sprintf("img/%d.png", i+1))
+		g.images[i] = loadImage(t.Symbols[i])
 	}
 
-	return g
+	x := int32(t.Layout.Side[0] - 25)
+	g.buttons = []Button{
+		{sdl.Rect{x, 95, 70, 30}, "Bet−", actBetDown},
+		{sdl.Rect{x + 80, 95, 70, 30}, "Bet+", actBetUp},
+		{sdl.Rect{x, 135, 150, 30}, "Help", actHelp},
+		{sdl.Rect{x, 395, 150, 40}, "Spin", actSpin},
+		{sdl.Rect{x, 442, 150, 30}, "Cash Out", actEndGame},
+	}
 }
 
-func (g *Game) reset() {
+func (g *Game) reset(slot int) {
+	g.slot = slot
+	g.over = false
+	g.stop = stopNone
+	g.cashed = 0
+	g.celebration = nil
+	g.voucher = nil
+	profile.updateLimits()
 	g.mut = false
 	g.keys = true
-	g.credit = 20
+	g.credit = cabinet.StartCredit
 	g.bet = 1
+	if conf.kiosk {
+		g.credit = 0
+		g.bet = 0
+	}
 	g.lastwin = 0
+	g.spins = 0
 	for i := range g.wins {
 		g.wins[i] = 0
 	}
-	for i := range g.show {
-		g.show[i] = 8
+	g.show = newShow()
+	profile.stats.Games++
+	sasPublish(g.credit)
+}
+
+// newShow returns the grid a new game starts on.
+func newShow() [9]int {
+	var show [9]int
+	for i := range show {
+		show[i] = numSymbols
 	}
-	playMusic(g.bgsound)
+	return show
+}
+
+func (g *Game) restore(slot int, s *SaveGame) {
+	g.slot = slot
+	g.over = false
+	g.stop = stopNone
+	g.cashed = 0
+	g.celebration = nil
+	g.voucher = nil
+	profile.updateLimits()
+	g.keys = true
+	g.credit = s.Credit
+	g.bet = s.Bet
+	g.lastwin = s.LastWin
+	g.spins = s.Spins
+	g.show = s.Show
+	g.showOld = s.Show
+	g.wins = s.Wins
+	g.mut = s.Spun
+	g.linebet = s.LineBet
+	if g.linebet == 0 {
+		g.linebet = s.Bet
+	}
+	g.presented = time.Now()
+	sasPublish(g.credit)
+}
+
+func (g *Game) save() {
+	if g.over || g.credit == 0 {
+		removeSave(g.slot)
+		return
+	}
+
+	s := &SaveGame{
+		Credit:  g.credit,
+		Bet:     g.bet,
+		LineBet: g.linebet,
+		LastWin: g.lastwin,
+		Spins:   g.spins,
+		Show:    g.show,
+		Wins:    g.wins,
+		Spun:    g.mut,
+	}
+	nk(s.write(g.slot))
+}
+
+// leave saves the game and the profile statistics,
+// it is called whenever the game scene is left.
+func (g *Game) leave() {
+	g.save()
+	profile.saveStats()
 }
 
 func (g *Game) Run() {
-	defer func() {
-		recover()
-	}()
+	defer g.leave()
 
-	g.reset()
+	playMusic(g.bgsound)
 	for {
+		if idle() && g.credit == 0 {
+			stopMusic()
+			state = intro
+			break
+		}
+
 		screen.SetDrawColor(sdlcolor.Black)
 		screen.Clear()
 		g.background.Blit(0, 0)
@@ -101,31 +213,63 @@ func (g *Game) Run() {
 }
 
 func (g *Game) event() bool {
+	if n := transferred; n > 0 {
+		transferred = 0
+		g.addCredit(n, func(s *MeterSet) {
+			s.Inserted += n
+		})
+	}
+
 	for {
-		ev := sdl.PollEvent()
+		ev := pollEvent(g.buttons...)
 		if ev == nil {
 			break
 		}
 		switch ev := ev.(type) {
 		case sdl.QuitEvent:
-			os.Exit(0)
+			if !conf.kiosk {
+				g.leave()
+				os.Exit(0)
+			}
 		case sdl.KeyDownEvent:
+			input()
 			playSound(g.bsound)
-			if (ev.Sym == sdl.K_LEFT || ev.Sym == sdl.K_RIGHT) && g.keys {
-				if g.credit > 0 {
-					if g.credit-g.bet < 0 {
-						g.bet = g.credit
-					}
-					if !conf.invincible {
-						g.credit -= g.bet
+			if attendant(ev) {
+				stopMusic()
+				state = operatorLogin(menu.Run)
+				return true
+			}
+			if coin(ev) {
+				if locked {
+					notify("The machine is locked, coin returned")
+				} else {
+					g.insertCoin()
+				}
+				continue
+			}
+			if bound(ev.Sym, actMute) {
+				toggleMute()
+				continue
+			}
+			if g.celebration != nil {
+				g.celebration.skip()
+				continue
+			}
+			if g.menu == "l" || g.menu == "r" {
+				g.acknowledge(ev.Sym)
+				continue
+			}
+
+			if bound(ev.Sym, actSpin) && g.keys {
+				if g.credit > 0 && !locked && !g.checkLimits() {
+					if g.spin() {
+						stopMusic()
+						menu.Reset()
+						state = menu.Run
+						return true
 					}
-					g.randi()
-					g.check()
-					g.roll()
-					g.background.Blit(0, 0)
-					g.drawl()
-					g.winner()
 				} else if g.credit == 0 && g.bet == 0 {
+					g.over = true
 					stopMusic()
 					menu.Reset()
 					state = menu.Run
@@ -134,7 +278,7 @@ func (g *Game) event() bool {
 			}
 
 			if g.credit > 0 {
-				if ev.Sym == sdl.K_UP && g.keys {
+				if bound(ev.Sym, actBetUp) && g.keys {
 					if g.credit-g.bet-1 >= 0 {
 						g.bet++
 					} else {
@@ -144,7 +288,7 @@ func (g *Game) event() bool {
 					if g.bet >= 11 {
 						g.bet = 1
 					}
-				} else if ev.Sym == sdl.K_DOWN && g.keys {
+				} else if bound(ev.Sym, actBetDown) && g.keys {
 					if g.bet--; g.bet <= 0 {
 						g.bet = 10
 					}
@@ -153,7 +297,7 @@ func (g *Game) event() bool {
 				g.bet = 0
 			}
 
-			if ev.Sym == sdl.K_F1 {
+			if bound(ev.Sym, actHelp) {
 				if g.keys {
 					g.menu = "h"
 				} else {
@@ -162,45 +306,192 @@ func (g *Game) event() bool {
 				g.keys = !g.keys
 			}
 
-			if ev.Sym == sdl.K_RETURN {
+			if ev.Sym == sdl.K_t && g.keys && !locked {
+				state = g.ticketIn()
+				return true
+			}
+
+			if bound(ev.Sym, actEndGame) && g.menu != "e" && g.cashOut() {
 				g.keys = false
 				g.menu = "e"
 			}
 
-			if ev.Sym == sdl.K_ESCAPE && g.keys {
+			if bound(ev.Sym, actBack) && g.keys {
 				stopMusic()
 				menu.Reset()
 				state = menu.Run
 				return true
 			}
+		case Click:
+			if g.celebration != nil {
+				g.celebration.skip()
+			}
 		}
 	}
 
 	return false
 }
 
+// spin plays one game. Every step is written to the journal
+// before the next one starts, so a spin cut short by a crash
+// can be settled on the next start. Leaving while the reels
+// spin lands them right away, and spin reports that the game
+// should return to the menu once the win is paid.
+func (g *Game) spin() bool {
+	j := g.place()
+	g.roll()
+	g.background.Blit(0, 0)
+	g.drawl()
+	return g.pay(j)
+}
+
+// place takes the bet and draws the outcome of a spin.
+func (g *Game) place() *Journal {
+	if g.credit-g.bet < 0 {
+		g.bet = g.credit
+	}
+
+	j := &Journal{
+		Slot:     g.slot,
+		Paytable: cabinet.Paytable,
+		Credit:   g.credit,
+		Bet:      g.bet,
+		Before:   g.show,
+	}
+	if !conf.invincible {
+		j.Cost = g.bet
+	}
+	g.credit -= j.Cost
+	session.net -= j.Cost
+	g.spins++
+	j.Spins = g.spins
+	profile.stats.Spins++
+	profile.stats.Bet += g.bet
+	meters.add(func(s *MeterSet) {
+		s.CoinIn += j.Cost
+		s.Played++
+	})
+	nk(j.write(spinBet))
+	sasException(sasGameStart)
+
+	j.Draws = g.randi()
+	g.wins = lines(g.show)
+	j.Show = g.show
+	j.Wins = g.wins
+	j.Payout = payout(g.wins, g.bet)
+	nk(j.write(spinOutcome))
+	return j
+}
+
+// pay pays the win of a spin once the reels landed, and acts on
+// what the player asked for while they were spinning.
+func (g *Game) pay(j *Journal) bool {
+	g.winner()
+	g.celebration = newCelebration(g.wins, g.lastwin, g.bet)
+	g.linebet = g.bet
+	session.net += g.lastwin
+	nk(j.write(spinSettled))
+
+	nk(j.audit(g.credit, ""))
+	saveMeters()
+	nk(j.write(spinRecorded))
+	g.save()
+	clearJournal()
+	sasException(sasGameEnd)
+	sasPublish(g.credit)
+
+	stop := g.stop
+	g.stop = stopNone
+	switch stop {
+	case stopExit:
+		g.leave()
+		os.Exit(0)
+	case stopMenu:
+		return true
+	}
+	return false
+}
+
+// pending is the part of the last win the meters haven't counted
+// up to yet.
+func (g *Game) pending() int {
+	if g.celebration == nil {
+		return 0
+	}
+	return g.celebration.amount - g.celebration.counted()
+}
+
+// insertCoin adds the credits of a coin on a kiosk.
+func (g *Game) insertCoin() {
+	g.addCredit(conf.coinValue, func(s *MeterSet) {
+		s.Inserted += conf.coinValue
+	})
+}
+
+// addCredit puts credits inserted, transferred or redeemed on the
+// credit meter, meter counts them on the machine meters.
+func (g *Game) addCredit(n int, meter func(s *MeterSet)) {
+	g.credit = settle(g.credit, n)
+	if g.bet == 0 {
+		g.bet = 1
+	}
+	meters.add(meter)
+	saveMeters()
+	g.save()
+	sasPublish(g.credit)
+}
+
 func (g *Game) draw() bool {
 	g.drawSide()
 
+	if c := g.celebration; c != nil {
+		c.update()
+		if c.done() {
+			g.celebration = nil
+			g.presented = time.Now()
+		}
+	}
+
 	if g.mut {
 		g.drawl()
 		g.check()
 		for i := range g.wins {
 			g.wins[i] = 0
 		}
+		if g.celebration != nil {
+			g.celebration.drawCells()
+		}
 	}
 
-	if g.credit == 0 && g.bet == 0 {
-		blitText(g.creditFont, 70, 190, sdlcolor.Red, "Game Over")
+	if locked {
+		blitText(g.creditFont, 70, 190, theme.Colors.PanelText.Color, tr("Locked"))
+	} else if g.credit == 0 && g.bet == 0 {
+		if conf.kiosk {
+			blitText(g.creditFont, 70, 190, theme.Colors.PanelText.Color, tr("Insert Coin"))
+		} else {
+			blitText(g.creditFont, 70, 190, theme.Colors.PanelText.Color, tr("Game Over"))
+		}
+		blitText(g.font, 70, 260, theme.Colors.PanelText.Color, tr("Press T to insert a ticket"))
 	}
 
-	g.rlayer.Blit(37, 48)
+	g.rlayer.Blit(theme.Layout.ReelLayer[0], theme.Layout.ReelLayer[1])
 	g.windowLayer.Blit(0, 0)
 
+	if g.celebration != nil {
+		g.celebration.drawBanner(g)
+	}
+	g.drawLineWin()
+
+	if g.keys {
+		g.checkLimits()
+	}
+
 	if !g.keys {
 		switch g.menu {
 		case "h":
 			g.helpMenu()
+		case "l", "r":
+			g.limitMenu()
 		case "e":
 			if g.endGame() {
 				return true
@@ -208,207 +499,224 @@ func (g *Game) draw() bool {
 		}
 	}
 
+	drawNotices()
 	screen.Present()
 	return false
 }
 
 func (g *Game) drawSide() {
+	c := &theme.Colors
+	d := theme.Layout.Display
+	x, y := theme.Layout.Side[0], theme.Layout.Side[1]
+
 	// animation
-	blitText(g.digiFont, 470, 50, sdl.Color{60, 0, 0, 255}, "88888888888")
+	blitText(g.digiFont, d[0], d[1], c.DigitsOff.Color, "88888888888")
 
-	blitText(g.digiFont, 470, 50, sdlcolor.White, "F1 FOR HELP")
+	blitText(g.digiFont, d[0], d[1], c.Text.Color, tr("F1 FOR HELP"))
 
-	blitText(g.font, 500, 185, sdl.Color{230, 255, 255, 255}, "Bet:")
+	blitText(g.font, x, y, c.Label.Color, tr("Bet:"))
 
 	// multip
-	blitText(g.digiFont, 500, 210, sdl.Color{60, 0, 0, 255}, "88")
+	blitText(g.digiFont, x, y+25, c.DigitsOff.Color, "88")
 
-	blitText(g.digiFont, 500, 210, sdl.Color{255, 0, 0, 255}, fmt.Sprintf("%02d", g.bet))
+	blitText(g.digiFont, x, y+25, c.Digits.Color, fmt.Sprintf("%02d", g.bet))
 
-	blitText(g.font, 500, 255, sdl.Color{230, 255, 255, 255}, "Winner Paid:")
+	blitText(g.font, x, y+70, c.Label.Color, tr("Winner Paid:"))
 
 	// last win
-	blitText(g.digiFont, 500, 280, sdl.Color{60, 0, 0, 255}, "888")
+	blitText(g.digiFont, x, y+95, c.DigitsOff.Color, "888")
 
-	blitText(g.digiFont, 500, 280, sdl.Color{255, 0, 0, 255}, fmt.Sprintf("%03d", g.lastwin))
+	blitText(g.digiFont, x, y+95, c.Digits.Color, fmt.Sprintf("%03d", g.lastwin-g.pending()))
 
-	blitText(g.font, 500, 325, sdl.Color{230, 255, 255, 255}, "Credit:")
+	blitText(g.font, x, y+140, c.Label.Color, tr("Credit:"))
 
 	// startsum
-	blitText(g.digiFont, 500, 350, sdl.Color{60, 0, 0, 255}, "888888")
+	blitText(g.digiFont, x, y+165, c.DigitsOff.Color, "888888")
 
-	blitText(g.digiFont, 500, 350, sdl.Color{255, 0, 0, 255}, fmt.Sprintf("%06d", g.credit))
+	blitText(g.digiFont, x, y+165, c.Digits.Color, fmt.Sprintf("%06d", g.credit-g.pending()))
 
+	for i := range g.buttons {
+		g.buttons[i].draw(g.font)
+	}
 }
 
 func (g *Game) drawl() {
-	xs := [3]int{36, 165, 295}
-	ys := [3]int{46, 174, 302}
-
 	var i int
-	for _, x := range xs {
-		for _, y := range ys {
+	for _, x := range theme.Layout.ReelX {
+		for _, y := range theme.Layout.ReelY {
 			g.images[g.show[i]-1].Blit(x, y)
 			i++
 		}
 	}
 }
 
-func (g *Game) randi() {
+func (g *Game) randi() [9]int {
+	var draws [9]int
+
 	copy(g.showOld[:], g.show[:])
 	g.mut = true
 
+	pt := findPaytable(cabinet.Paytable)
 	for i := range g.show {
-		r := randn(1, 335)
-		n := 0
-		switch {
-		case 1 <= r && r <= 5:
-			n = 8
-		case 6 <= r && r <= 15:
-			n = 7
-		case 16 <= r && r <= 30:
-			n = 6
-		case 31 <= r && r <= 50:
-			n = 5
-		case 51 <= r && r <= 120:
-			n = 4
-		case 121 <= r && r <= 180:
-			n = 3
-		case 181 <= r && r <= 253:
-			n = 2
-		case 254 <= r && r <= 334:
-			n = 1
-		}
-
-		g.show[i] = n
+		draws[i] = randn(1, pt.total()+1)
+		g.show[i] = pt.symbol(draws[i])
 	}
+	return draws
 }
 
+// check draws the winning lines, all of them while the win is
+// celebrated and then one at a time with the other cells dimmed.
 func (g *Game) check() {
-	s := g.show
-	if s[0] == s[3] && s[3] == s[6] {
-		sdlgfx.ThickLine(screen.Renderer, 36, 111, 423, 111, 8, sdl.Color{246, 226, 0, 255})
-		g.wins[0] = s[0]
+	g.wins = lines(g.show)
+	if i, ok := g.presentedLine(); ok {
+		g.highlightCells(paylines[i])
+		e := theme.Layout.Lines[i]
+		sdlgfx.ThickLine(screen.Renderer, e[0], e[1], e[2], e[3], 8, theme.Colors.Lines.Color)
+		return
 	}
-	if s[1] == s[4] && s[4] == s[7] {
-		sdlgfx.ThickLine(screen.Renderer, 36, 239, 423, 239, 8, sdl.Color{246, 226, 0, 255})
-		g.wins[1] = s[1]
+	for i, n := range g.wins {
+		if n != 0 {
+			e := theme.Layout.Lines[i]
+			sdlgfx.ThickLine(screen.Renderer, e[0], e[1], e[2], e[3], 8, theme.Colors.Lines.Color)
+		}
 	}
-	if s[2] == s[5] && s[5] == s[8] {
-		sdlgfx.ThickLine(screen.Renderer, 36, 367, 423, 367, 8, sdl.Color{246, 226, 0, 255})
-		g.wins[2] = s[2]
+}
+
+// presentedLine returns the winning line shown now. The lines take
+// turns until the next spin.
+func (g *Game) presentedLine() (int, bool) {
+	if !g.mut || g.celebration != nil {
+		return 0, false
 	}
-	if s[0] == s[4] && s[4] == s[8] {
-		sdlgfx.ThickLine(screen.Renderer, 37, 47, 422, 433, 8, sdl.Color{246, 226, 0, 255})
-		g.wins[3] = s[0]
+
+	var won []int
+	for i, n := range lines(g.show) {
+		if n != 0 {
+			won = append(won, i)
+		}
 	}
-	if s[2] == s[4] && s[4] == s[6] {
-		sdlgfx.ThickLine(screen.Renderer, 37, 432, 422, 47, 8, sdl.Color{246, 226, 0, 255})
-		g.wins[4] = s[2]
+	if len(won) == 0 {
+		return 0, false
 	}
+	return won[int(time.Since(g.presented)/linePeriod)%len(won)], true
 }
 
-func (g *Game) genRollColumn(col, n int) []*Image {
-	var m []*Image
+// highlightCells lights up the cells of a line and dims the others.
+func (g *Game) highlightCells(line [3]int) {
+	l := &theme.Layout
+	w, h := l.ReelX[1]-l.ReelX[0], l.ReelY[1]-l.ReelY[0]
+	light := theme.Colors.Lines.Color
+	light.A = 64
+	for i := range g.show {
+		c := sdl.Color{0, 0, 0, 150}
+		if i == line[0] || i == line[1] || i == line[2] {
+			c = light
+		}
+		x, y := l.ReelX[i/3], l.ReelY[i%3]
+		sdlgfx.Box(screen.Renderer, x, y, x+w-1, y+h-1, c)
+	}
+}
 
-	img := g.images
-	s := g.show
-	m = append(m, img[s[n]-1])
-	m = append(m, img[s[n+1]-1])
-	m = append(m, img[s[n+2]-1])
-	for i := 0; i <= col-3; i++ {
-		m = append(m, img[randn(0, 8)])
+// drawLineWin tells what the line shown pays, under the reels.
+func (g *Game) drawLineWin() {
+	i, ok := g.presentedLine()
+	if !ok {
+		return
 	}
 
-	s = g.showOld
-	m = append(m, img[s[n]-1])
-	m = append(m, img[s[n+1]-1])
-	m = append(m, img[s[n+2]-1])
+	l := &theme.Layout
+	w, h := l.ReelX[1]-l.ReelX[0], l.ReelY[1]-l.ReelY[0]
+	x0, x1 := l.ReelX[0], l.ReelX[2]+w
+	y := l.ReelY[2] + h + 18
+	sdlgfx.ThickLine(screen.Renderer, x0, y, x1, y, 26, theme.Colors.Panel.Color)
 
-	return m
+	n := lines(g.show)[i]
+	text := trf("Line %d: %d × bet = %s", i+1, n+1, num(linePay(n, g.linebet)))
+	blitCentered(g.font, (x0+x1)/2, y-9, theme.Colors.PanelText.Color, text)
 }
 
-func (g *Game) rollColumn(r []*Image, l, x int) ([]*Image, int) {
-	if l > 2 {
-		r[len(r)-3].Blit(x, 46)
-		r[len(r)-2].Blit(x, 174)
-		r[len(r)-1].Blit(x, 302)
-		l--
-		r = r[:len(r)-1]
-	} else {
-		r[len(r)-3].Blit(x, 46)
-		r[len(r)-2].Blit(x, 174)
-		r[len(r)-1].Blit(x, 302)
-	}
+// The reel speeds, by how many times faster than normal the reels
+// stop.
+var speeds = []string{"normal", "fast", "turbo"}
 
-	return r, l
+func validSpeed(speed string) bool {
+	return speedIndex(speed) >= 0
 }
 
-func (g *Game) roll() {
-	// toll time
-	a := randn(5, 14)
-	b := randn(a+1, a+5)
-	c := randn(b+1, b+5)
-
-	ra := g.genRollColumn(a, 0)
-	ca := playSound(g.rsound)
+func speedIndex(speed string) int {
+	for i, s := range speeds {
+		if s == speed {
+			return i
+		}
+	}
+	return -1
+}
 
-	rb := g.genRollColumn(b, 3)
-	cb := playSound(g.rsound)
+func speedFactor() int {
+	return speedIndex(conf.speed) + 1
+}
 
-	rc := g.genRollColumn(c, 6)
-	cc := playSound(g.rsound)
+func nextSpeed() string {
+	return speeds[(speedIndex(conf.speed)+1)%len(speeds)]
+}
 
-	la := len(ra) - 1
-	lb := len(rb) - 1
-	lc := len(rc) - 1
+// roll spins the reels to the symbols drawn, until they all settle
+// or the player leaves.
+func (g *Game) roll() {
+	var reels [3]*Reel
+	for i := range reels {
+		reels[i] = g.newReel(i)
+	}
 
-	for lc > 2 {
-		g.qevent(ca, cb, cc)
+	start := time.Now()
+	for g.stop == stopNone {
+		g.qevent()
+		t := time.Since(start)
 
 		screen.SetDrawColor(sdlcolor.Black)
 		g.background.Blit(0, 0)
 
-		ra, la = g.rollColumn(ra, la, 36)
-		rb, lb = g.rollColumn(rb, lb, 165)
-		rc, lc = g.rollColumn(rc, lc, 295)
-
-		if la <= 2 {
-			sdlmixer.HaltChannel(ca)
-		}
-		if lb <= 2 {
-			sdlmixer.HaltChannel(cb)
-		}
-		if lc <= 2 {
-			sdlmixer.HaltChannel(cc)
+		settled := true
+		screen.SetClipRect(reelArea())
+		for _, r := range reels {
+			r.draw(t)
+			settled = settled && r.settled(t)
 		}
+		screen.SetClipRect(nil)
 
 		g.drawSide()
-		g.rlayer.Blit(37, 48)
+		g.rlayer.Blit(theme.Layout.ReelLayer[0], theme.Layout.ReelLayer[1])
 		g.windowLayer.Blit(0, 0)
 		screen.Present()
+		fps.Delay()
+
+		if settled {
+			break
+		}
+	}
+
+	for _, r := range reels {
+		sdlmixer.HaltChannel(r.channel)
 	}
 }
 
-func (g *Game) qevent(ca, cb, cc int) {
+func (g *Game) qevent() {
 	for {
-		ev := sdl.PollEvent()
+		ev := pollEvent()
 		if ev == nil {
 			break
 		}
 		switch ev := ev.(type) {
 		case sdl.QuitEvent:
-			os.Exit(0)
+			if !conf.kiosk {
+				g.stop = stopExit
+			}
 		case sdl.KeyDownEvent:
-			switch ev.Sym {
-			case sdl.K_ESCAPE:
-				sdlmixer.HaltChannel(ca)
-				sdlmixer.HaltChannel(cb)
-				sdlmixer.HaltChannel(cc)
-				stopMusic()
-				menu.Reset()
-				state = menu.Run
-				panic(nil)
+			if bound(ev.Sym, actBack) && g.stop == stopNone {
+				g.stop = stopMenu
+			}
+			if bound(ev.Sym, actMute) {
+				toggleMute()
 			}
 		}
 	}
@@ -417,55 +725,96 @@ func (g *Game) qevent(ca, cb, cc int) {
 func (g *Game) winner() {
 	g.lastwin = 0
 	for _, n := range g.wins {
-		winsu := g.bet * n
-		winsum := winsu + g.bet
-		if winsum > g.bet {
-			g.credit += winsum
+		if winsum := linePay(n, g.bet); winsum > 0 {
+			g.credit = settle(g.credit, winsum)
 			g.lastwin += winsum
 			playSound(g.beepsound)
 		}
-		if g.credit > maxScore {
-			g.credit = maxScore
-		}
 	}
+
+	meterPayout(g.wins, g.lastwin)
+
+	st := &profile.stats
+	st.Won += g.lastwin
+	if g.lastwin > st.BestWin {
+		st.BestWin = g.lastwin
+	}
+}
+
+// meterPayout counts what a spin paid on the meters.
+func meterPayout(wins [5]int, pay int) {
+	jackpot := false
+	for _, n := range wins {
+		jackpot = jackpot || n == numSymbols
+	}
+	meters.add(func(s *MeterSet) {
+		s.CoinOut += pay
+		if pay > 0 {
+			s.Won++
+		}
+		if jackpot {
+			s.Jackpots++
+		}
+	})
 }
 
 func (g *Game) helpMenu() {
-	sdlgfx.ThickLine(screen.Renderer, 50, 250, 590, 250, 400, sdl.Color{176, 176, 176, 255})
+	sdlgfx.ThickLine(screen.Renderer, 50, 250, 590, 250, 400, theme.Colors.Panel.Color)
 
 	y := 250 - 120
-	blitText(g.font, 60, y+60, sdlcolor.Red, "How to play:")
-	blitText(g.font, 60, y+80, sdlcolor.Red, "New spin: left or right arrow")
-	blitText(g.font, 60, y+100, sdlcolor.Red, "Raise bet: up arrow")
-	blitText(g.font, 60, y+120, sdlcolor.Red, "To end game to high score press Enter")
-	blitText(g.font, 60, y+160, sdlcolor.Red, "To close this as game over help press F1")
+	blitText(g.font, 60, y+60, theme.Colors.PanelText.Color, tr("How to play:"))
+	blitText(g.font, 60, y+80, theme.Colors.PanelText.Color, trf("New spin: %s", keyNames(actSpin)))
+	blitText(g.font, 60, y+100, theme.Colors.PanelText.Color, trf("Raise bet: %s, lower bet: %s", keyNames(actBetUp), keyNames(actBetDown)))
+	blitText(g.font, 60, y+120, theme.Colors.PanelText.Color, trf("To end game to high score press %s", keyNames(actEndGame)))
+	blitText(g.font, 60, y+140, theme.Colors.PanelText.Color, trf("Mute: %s", keyNames(actMute)))
+	blitText(g.font, 60, y+160, theme.Colors.PanelText.Color, trf("To close this as game over help press %s", keyNames(actHelp)))
 }
 
 func (g *Game) endGame() bool {
-	sdlgfx.ThickLine(screen.Renderer, 50, 250, 590, 250, 400, sdl.Color{176, 176, 176, 255})
+	sdlgfx.ThickLine(screen.Renderer, 50, 250, 590, 250, 400, theme.Colors.Panel.Color)
 
-	if g.credit > score {
+	rank := profile.scores.Rank(g.cashed)
+	if rank >= 0 {
 		y := 250 - 110
-		blitText(g.font, 60, y+60, sdlcolor.Red, "You have a new high score!!!")
-		blitText(g.font, 60, y+80, sdlcolor.Red, fmt.Sprint("Old high score: ", score))
-		blitText(g.font, 60, y+100, sdlcolor.Red, fmt.Sprint("New high score: ", g.credit))
-		saveScore(score)
+		blitText(g.font, 60, y+60, theme.Colors.PanelText.Color, tr("You have a new high score!!!"))
+		blitText(g.font, 60, y+80, theme.Colors.PanelText.Color, trf("Best high score: %s", num(profile.scores.Best())))
+		blitText(g.font, 60, y+100, theme.Colors.PanelText.Color, trf("Your score: %s (rank %d)", num(g.cashed), rank+1))
+		blitText(g.font, 60, y+140, theme.Colors.PanelText.Color, tr("Press any key or tap to enter your initials"))
 	} else {
 		y := 180
-		blitText(g.font, 100, y+60, sdlcolor.Red, "You ended the game, but you don't have a new high score...")
+		blitText(g.font, 100, y+60, theme.Colors.PanelText.Color, tr("You ended the game, but you don't have a new high score..."))
+	}
+
+	if v := g.voucher; v != nil {
+		y := 250 + 80
+		blitText(g.font, 60, y, theme.Colors.PanelText.Color, trf("Voucher printed for %s credits", num(v.Amount)))
+		blitText(g.font, 60, y+20, theme.Colors.PanelText.Color, trf("Validation number: %s", formatValidation(v.Number)))
 	}
 
 	for {
-		ev := sdl.PollEvent()
+		ev := pollEvent()
 		if ev == nil {
 			break
 		}
 		switch ev.(type) {
-		case sdl.KeyDownEvent:
+		case sdl.KeyDownEvent, Click:
+			g.over = true
 			stopMusic()
 			state = menu.Run
-			if g.credit > score {
-				score = g.credit
+			if rank >= 0 {
+				e := ScoreEntry{
+					Score: g.cashed,
+					Date:  time.Now(),
+					Spins: g.spins,
+				}
+				state = newInitials("Enter your initials:", profile.name, func(name string) error {
+					if name == "" {
+						return errors.New(tr("initials are empty"))
+					}
+					e.Name = name
+					profile.scores.Insert(e)
+					return profile.scores.save(profile.dir)
+				}, hiscore.Run).Run
 			}
 			return true
 		}
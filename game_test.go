@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func newTestGame(t *testing.T) *Game {
+	dir := t.TempDir()
+	conf.pref = dir
+	conf.sound = false
+	conf.invincible = false
+	conf.kiosk = false
+	profile = &Profile{name: "test", dir: dir}
+	meters = Meters{}
+	rand.Seed(1)
+
+	g := &Game{}
+	g.reset(0)
+	return g
+}
+
+// TestSpinStoppedConservesCredit leaves mid-roll on every spin and
+// checks the spin is still paid in full and saved.
+func TestSpinStoppedConservesCredit(t *testing.T) {
+	g := newTestGame(t)
+	g.credit = 1000
+	g.bet = 3
+
+	for i := 0; i < 200; i++ {
+		before := g.credit
+		j := g.place()
+		g.stop = stopMenu
+		if !g.pay(j) {
+			t.Fatalf("spin %d: leaving mid-roll did not return to the menu", i)
+		}
+		if g.stop != stopNone {
+			t.Fatalf("spin %d: stop request not cleared", i)
+		}
+		if g.lastwin != j.Payout {
+			t.Fatalf("spin %d: paid %d, journal says %d", i, g.lastwin, j.Payout)
+		}
+		if g.credit != before-j.Cost+j.Payout {
+			t.Fatalf("spin %d: credit %d, want %d - %d + %d", i, g.credit, before, j.Cost, j.Payout)
+		}
+		if _, err := os.Stat(journalName()); !os.IsNotExist(err) {
+			t.Fatalf("spin %d: journal left behind: %v", i, err)
+		}
+		s, err := loadSave(g.slot)
+		if err != nil {
+			t.Fatalf("spin %d: %v", i, err)
+		}
+		if s.Credit != g.credit {
+			t.Fatalf("spin %d: saved credit %d, want %d", i, s.Credit, g.credit)
+		}
+	}
+
+	m := &meters.Lifetime
+	if m.CoinIn != 200*3 || 1000-m.CoinIn+m.CoinOut != g.credit {
+		t.Fatalf("meters in %d out %d don't add up to credit %d", m.CoinIn, m.CoinOut, g.credit)
+	}
+}
+
+// crashSpin plays a spin up to the given journal state and leaves
+// it there, as a crash would.
+func crashSpin(t *testing.T, g *Game, state string) *Journal {
+	j := g.place()
+	if state == spinBet {
+		if err := j.write(spinBet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return j
+}
+
+// lastAudit returns the last entry of the audit log.
+func lastAudit(t *testing.T) *AuditEntry {
+	buf, err := ioutil.ReadFile(auditName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(buf), []byte("\n"))
+	e := &AuditEntry{}
+	if err := json.Unmarshal(lines[len(lines)-1], e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRecoverSpinConservesCredit(t *testing.T) {
+	for _, state := range []string{spinBet, spinOutcome} {
+		for _, saved := range []bool{true, false} {
+			g := newTestGame(t)
+			g.credit = 50
+			g.bet = 5
+			if saved {
+				g.save()
+			}
+
+			var j *Journal
+			for j == nil || (state == spinOutcome && j.Payout == 0) {
+				removeSave(g.slot)
+				if saved {
+					g.save()
+				}
+				j = crashSpin(t, g, state)
+				g.credit = j.Credit
+				g.show = j.Before
+			}
+
+			meters = Meters{}
+			recoverSpin()
+
+			want, recovered, coinIn := j.Credit, auditRefunded, 0
+			if state == spinOutcome {
+				want, recovered, coinIn = j.Credit-j.Cost+j.Payout, auditSettled, j.Cost
+			}
+			e := lastAudit(t)
+			if e.Recovered != recovered || e.Before != j.Credit || e.After != want {
+				t.Fatalf("%s, saved %v: audited %+v", state, saved, e)
+			}
+			if p := e.verify(); p != nil {
+				t.Fatalf("%s, saved %v: %v", state, saved, p)
+			}
+			if m := meters.Lifetime; m.CoinIn != coinIn || m.CoinOut != want-j.Credit+coinIn {
+				t.Fatalf("%s, saved %v: meters in %d out %d", state, saved, m.CoinIn, m.CoinOut)
+			}
+			s, err := loadSave(j.Slot)
+			if err != nil {
+				t.Fatalf("%s, saved %v: %v", state, saved, err)
+			}
+			if s.Credit != want {
+				t.Fatalf("%s, saved %v: credit %d, want %d", state, saved, s.Credit, want)
+			}
+			for _, n := range s.Show {
+				if n < 1 || n > len(g.images) {
+					t.Fatalf("%s, saved %v: recovered grid %v", state, saved, s.Show)
+				}
+			}
+			if _, err := os.Stat(journalName()); !os.IsNotExist(err) {
+				t.Fatalf("%s, saved %v: journal left behind: %v", state, saved, err)
+			}
+		}
+	}
+}
+
+// TestRestoreLineBet changes the bet after a spin and checks the
+// continued game still shows the lines at the bet they were won at.
+func TestRestoreLineBet(t *testing.T) {
+	g := newTestGame(t)
+	g.credit = 100
+	g.bet = 3
+	g.pay(g.place())
+	g.bet = 5
+	g.save()
+
+	s, err := loadSave(g.slot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.restore(g.slot, s)
+	if g.bet != 5 || g.linebet != 3 {
+		t.Fatalf("restored bet %d, line bet %d, want 5 and 3", g.bet, g.linebet)
+	}
+}
+
+// TestCashOutClearsSave cashes out and checks the slot can't be
+// continued with the credit the voucher was printed for.
+func TestCashOutClearsSave(t *testing.T) {
+	g := newTestGame(t)
+	g.credit = 75
+	g.save()
+
+	if !g.cashOut() {
+		t.Fatal("no voucher issued")
+	}
+	if g.voucher == nil || g.voucher.Amount != 75 {
+		t.Fatalf("voucher %+v, want 75 credits", g.voucher)
+	}
+	s, err := loadSave(g.slot)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		t.Fatal(err)
+	case s.Credit != 0:
+		t.Fatalf("slot still holds %d credits", s.Credit)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/qeedquan/go-media/sdl"
+	"github.com/qeedquan/go-media/sdl/sdlimage/sdlcolor"
+	"github.com/qeedquan/go-media/sdl/sdlttf"
+)
+
+// HighScores is the scene listing the high score table of the
+// active profile, with the statistics of its play under it.
+type HighScores struct {
+	font *sdlttf.Font
+}
+
+func newHighScores() *HighScores {
+	h := &HighScores{}
+	h.loadTheme()
+	return h
+}
+
+func (h *HighScores) loadTheme() {
+	h.font = loadFont(theme.Fonts.Text, 20)
+}
+
+func (h *HighScores) Run() {
+	for {
+		if idle() {
+			state = intro
+			return
+		}
+		if h.event() {
+			return
+		}
+		h.draw()
+		sdl.Delay(1000 / 60)
+	}
+}
+
+func (h *HighScores) event() bool {
+	for {
+		ev := pollEvent()
+		if ev == nil {
+			break
+		}
+		switch ev := ev.(type) {
+		case sdl.QuitEvent:
+			quit()
+		case sdl.KeyDownEvent:
+			if bound(ev.Sym, actBack) || bound(ev.Sym, actConfirm) {
+				playSound(menu.bsound)
+				state = menu.Run
+				return true
+			}
+		case Click:
+			playSound(menu.bsound)
+			state = menu.Run
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HighScores) draw() {
+	m := menu
+	c := theme.Colors.Text.Color
+
+	screen.SetDrawColor(sdlcolor.Black)
+	screen.Clear()
+
+	m.drawSlide()
+	m.background.Blit(0, 0)
+	for y := 60; y < 480; y += 60 {
+		m.sav.Blit(0, y)
+	}
+	m.highScore.Blit(490, 70)
+
+	blitText(m.font, 50, 15, c, tr("High score")+" - "+profile.name)
+
+	const (
+		rank  = 50
+		name  = 90
+		score = 190
+		date  = 290
+		spins = 420
+	)
+	y := 80
+	blitText(m.smallFont, name, y, c, tr("Name"))
+	blitText(m.smallFont, score, y, c, tr("Score"))
+	blitText(m.smallFont, date, y, c, tr("Date"))
+	blitText(m.smallFont, spins, y, c, tr("Spins"))
+
+	entries := profile.scores.Entries
+	if len(entries) == 0 {
+		blitText(h.font, name, 120, c, tr("No high scores yet"))
+	}
+	for i, e := range entries {
+		y := 110 + i*34
+		blitText(h.font, rank, y, c, fmt.Sprintf("%d.", i+1))
+		blitText(h.font, name, y, c, e.Name)
+		blitText(h.font, score, y, c, num(e.Score))
+		if !e.Date.IsZero() {
+			blitText(h.font, date, y, c, e.Date.Format(locale.Date))
+		}
+		blitText(h.font, spins, y, c, num(e.Spins))
+	}
+
+	st := &profile.stats
+	blitText(m.smallFont, rank, 452, c, trf("Games: %s  Spins: %s  Bet: %s  Won: %s  Best win: %s",
+		num(st.Games), num(st.Spins), num(st.Bet), num(st.Won), num(st.BestWin)))
+
+	m.tick()
+	drawNotices()
+	screen.Present()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const defaultLanguage = "en"
+
+// Locale is a message catalog, kept as lang/<code>.json in the
+// assets. Messages are looked up by their English text, which is
+// also what is shown for a message the catalog is missing.
+type Locale struct {
+	Name      string
+	Thousands string
+	Date      string
+	DateTime  string
+	Messages  map[string]string
+}
+
+var (
+	locale = &Locale{
+		Name:      "English",
+		Thousands: ",",
+		Date:      "2006-01-02",
+		DateTime:  "Jan 2 15:04",
+	}
+)
+
+func localeName(code string) string {
+	return path.Join("lang", code+".json")
+}
+
+// listLanguages returns the codes of the message catalogs.
+func listLanguages() []string {
+	var codes []string
+	for _, name := range vfs.readDir("lang") {
+		if strings.HasSuffix(name, ".json") {
+			codes = append(codes, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	return codes
+}
+
+func loadLocale(code string) (*Locale, error) {
+	buf, err := readAsset(localeName(code))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Locale{}
+	if err := json.Unmarshal(buf, l); err != nil {
+		return nil, fmt.Errorf("language %s: %v", code, err)
+	}
+	return l, nil
+}
+
+// setLanguage switches to the named message catalog, falling back
+// to English if it can't be loaded, and lays the menus out again
+// for the width of the new texts.
+func setLanguage(code string) {
+	l, err := loadLocale(code)
+	if err != nil {
+		notify("%v", err)
+		code = defaultLanguage
+		l, err = loadLocale(code)
+	}
+	ck(err)
+
+	conf.lang = code
+	locale = l
+	if menu == nil {
+		return
+	}
+	for _, m := range menus() {
+		m.Refresh()
+	}
+}
+
+// nextLanguage returns the language after the current one.
+func nextLanguage() string {
+	codes := listLanguages()
+	for i, code := range codes {
+		if code == conf.lang {
+			return codes[(i+1)%len(codes)]
+		}
+	}
+	return defaultLanguage
+}
+
+// tr translates a message.
+func tr(msg string) string {
+	if s, found := locale.Messages[msg]; found {
+		return s
+	}
+	return msg
+}
+
+// trf translates a format and formats it.
+func trf(format string, args ...interface{}) string {
+	return fmt.Sprintf(tr(format), args...)
+}
+
+// choice formats a translated menu choice.
+func choice(format string, args ...interface{}) string {
+	return "  " + trf(format, args...) + "  "
+}
+
+// num formats a number with the thousands separator of the locale.
+func num(n int) string {
+	s := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + locale.Thousands + s[i:]
+	}
+	return sign + s
+}
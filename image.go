@@ -2,7 +2,6 @@ package main
 
 import (
 	"log"
-	"path/filepath"
 
 	"github.com/qeedquan/go-media/sdl"
 	"github.com/qeedquan/go-media/sdl/sdlimage"
@@ -20,13 +19,14 @@ var (
 
 func loadImage(name string) *Image {
 	log.SetPrefix("image: ")
-	filename := filepath.Join(conf.assets, name)
-
-	if m, found := images[filename]; found {
+	if m, found := images[name]; found {
 		return m
 	}
 
-	texture, err := sdlimage.LoadTextureFile(screen.Renderer, filename)
+	rw, err := openAsset(name, false)
+	ck(err)
+
+	texture, err := sdlimage.LoadTextureRW(screen.Renderer, rw, true)
 	ck(err)
 
 	texture.SetBlendMode(sdl.BLENDMODE_BLEND)
@@ -37,17 +37,31 @@ func loadImage(name string) *Image {
 		w:       w,
 		h:       h,
 	}
-	images[filename] = m
+	images[name] = m
 	return m
 }
 
+// purgeImages frees the cached images, so the ones of another
+// theme can be loaded.
+func purgeImages() {
+	for name, m := range images {
+		m.Destroy()
+		delete(images, name)
+	}
+}
+
 func (m *Image) Blit(x, y int) {
 	screen.Copy(m.Texture, nil, &sdl.Rect{int32(x), int32(y), int32(m.w), int32(m.h)})
 }
 
+// BlitSize draws the image scaled to w by h.
+func (m *Image) BlitSize(x, y, w, h int) {
+	screen.Copy(m.Texture, nil, &sdl.Rect{int32(x), int32(y), int32(w), int32(h)})
+}
+
 type fontKey struct {
-	filename string
-	ptsize   int
+	name   string
+	ptsize int
 }
 
 var (
@@ -57,15 +71,24 @@ var (
 func loadFont(name string, ptsize int) *sdlttf.Font {
 	log.SetPrefix("font: ")
 
-	filename := filepath.Join(conf.assets, name)
-	key := fontKey{filename, ptsize}
+	key := fontKey{name, ptsize}
 	if font, found := fonts[key]; found {
 		return font
 	}
 
-	font, err := sdlttf.OpenFont(filename, ptsize)
+	rw, err := openAsset(name, true)
+	ck(err)
+
+	font, err := sdlttf.OpenFontRW(rw, true, ptsize)
 	ck(err)
 
 	fonts[key] = font
 	return font
 }
+
+func purgeFonts() {
+	for key, font := range fonts {
+		font.Close()
+		delete(fonts, key)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const journalVersion = 1
+
+// The steps a spin goes through, each one is written to the
+// journal before the game moves on to the next. A recorded spin
+// is in the audit log and on the meters, a refunded one is in the
+// audit log.
+const (
+	spinBet      = "bet"
+	spinOutcome  = "outcome"
+	spinSettled  = "settled"
+	spinRecorded = "recorded"
+	spinRefunded = "refunded"
+)
+
+// Journal is the write-ahead record of the spin in progress.
+// It is removed once the spin has been settled and the game
+// saved, so finding one on startup means the game died mid-spin.
+type Journal struct {
+	State    string
+	Slot     int
+	Paytable string
+	Credit   int
+	Cost     int
+	Bet      int
+	Spins    int
+	Before   [9]int
+	Draws    [9]int
+	Show     [9]int
+	Wins     [5]int
+	Payout   int
+}
+
+func journalName() string {
+	return filepath.Join(profile.dir, "journal")
+}
+
+func (j *Journal) write(state string) error {
+	j.State = state
+	err := saveData(journalName(), "journal", journalVersion, j)
+	if err != nil {
+		return fmt.Errorf("spin journal: %v", err)
+	}
+	return nil
+}
+
+// audit writes the spin to the audit log, recovered tells how it
+// was recovered after a crash.
+func (j *Journal) audit(after int, recovered string) error {
+	e := &AuditEntry{
+		Time:      time.Now(),
+		Session:   session.id,
+		Profile:   profile.name,
+		Slot:      j.Slot,
+		Paytable:  j.Paytable,
+		Draws:     j.Draws,
+		Show:      j.Show,
+		Lines:     j.Wins,
+		Bet:       j.Bet,
+		Cost:      j.Cost,
+		Payout:    j.Payout,
+		Before:    j.Credit,
+		After:     after,
+		Recovered: recovered,
+	}
+	if recovered == auditRefunded {
+		e.Draws, e.Show, e.Lines = [9]int{}, [9]int{}, [5]int{}
+		e.Cost, e.Payout = 0, 0
+	}
+	return audit(e)
+}
+
+func clearJournal() {
+	for _, name := range []string{journalName(), journalName() + ".bak"} {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			notify("spin journal: %v", err)
+		}
+	}
+}
+
+// recoverSpin settles a spin that was interrupted before it
+// was paid out. If the outcome was already decided the win is
+// paid, otherwise the bet is refunded. Either way the spin goes
+// into the audit log, and a paid one onto the meters, unless it
+// got there before the crash.
+func recoverSpin() {
+	j := &Journal{}
+	_, err := loadData(journalName(), "journal", journalVersion, j)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		notify("An unfinished spin could not be recovered: %v", err)
+		clearJournal()
+		return
+	}
+
+	s, err := loadSave(j.Slot)
+	if err != nil {
+		s = &SaveGame{Bet: j.Bet, Show: j.Before}
+	}
+	s.Spins = j.Spins
+
+	switch j.State {
+	case spinBet, spinRefunded:
+		s.Credit = j.Credit
+		s.Spins--
+		if j.State == spinBet {
+			nk(j.audit(s.Credit, auditRefunded))
+			nk(j.write(spinRefunded))
+		}
+		notify("An unfinished spin was refunded: %d credits returned to slot %d", j.Cost, j.Slot+1)
+	default:
+		s.Credit = settle(j.Credit-j.Cost, j.Payout)
+		s.Show = j.Show
+		s.Wins = j.Wins
+		s.LastWin = j.Payout
+		s.LineBet = j.Bet
+		s.Spun = true
+		if j.State != spinRecorded {
+			meters.add(func(m *MeterSet) {
+				m.CoinIn += j.Cost
+				m.Played++
+			})
+			meterPayout(j.Wins, j.Payout)
+			nk(j.audit(s.Credit, auditSettled))
+			saveMeters()
+			nk(j.write(spinRecorded))
+		}
+		notify("An unfinished spin was settled: %d credits paid to slot %d", j.Payout, j.Slot+1)
+	}
+
+	// journals of older versions don't have the grid before the
+	// spin, start those games on the grid of a new one
+	for _, n := range s.Show {
+		if n == 0 {
+			s.Show = newShow()
+			break
+		}
+	}
+
+	if s.Credit == 0 {
+		removeSave(j.Slot)
+	} else if err := s.write(j.Slot); err != nil {
+		notify("%v", err)
+		return
+	}
+	clearJournal()
+}
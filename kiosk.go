@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/qeedquan/go-media/sdl"
+)
+
+// In kiosk mode the machine runs unattended in a showroom: the
+// player can't quit it, credits come from the coin key, idle
+// sessions go back to the attract loop and a failing scene goes
+// back to the menu instead of taking the process down.
+
+var (
+	lastInput = time.Now()
+	coinKey   sdl.Keycode
+)
+
+func initKiosk() {
+	if !conf.kiosk {
+		return
+	}
+	coinKey = sdl.GetKeyFromName(conf.coinKey)
+	if coinKey == sdl.K_UNKNOWN {
+		log.SetPrefix("kiosk: ")
+		log.Fatalf("unknown coin key %q", conf.coinKey)
+	}
+}
+
+// quit exits the game, unless running as a kiosk where only the
+// attendant can stop the machine from the operator menu.
+func quit() {
+	if conf.kiosk {
+		return
+	}
+	os.Exit(0)
+}
+
+// input records that the player did something, for idle tracking.
+func input() {
+	lastInput = time.Now()
+}
+
+// idle reports whether a kiosk has gone without input long enough
+// to go back to the attract loop.
+func idle() bool {
+	return conf.kiosk && time.Since(lastInput) > conf.idle
+}
+
+// attendant reports whether the key is the attendant combination,
+// Ctrl+Alt+O, which opens the operator menu on a kiosk.
+func attendant(ev sdl.KeyDownEvent) bool {
+	return conf.kiosk && ev.Sym == sdl.K_o &&
+		ev.Mod&sdl.KMOD_CTRL != 0 && ev.Mod&sdl.KMOD_ALT != 0
+}
+
+// coin reports whether the key inserts a coin on a kiosk.
+func coin(ev sdl.KeyDownEvent) bool {
+	return conf.kiosk && ev.Sym == coinKey
+}
+
+// runScene runs the current scene. On a kiosk a scene that fails
+// is logged and the machine goes back to the menu.
+func runScene() {
+	if conf.kiosk {
+		defer func() {
+			if err := recover(); err != nil {
+				log.SetPrefix("kiosk: ")
+				log.Printf("scene failed: %v\n%s", err, debug.Stack())
+				stopMusic()
+				menu.Reset()
+				state = menu.Run
+				notify("Something went wrong, please try again")
+			}
+		}()
+	}
+	state()
+}
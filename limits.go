@@ -0,0 +1,249 @@
+package main
+
+import (
+	"time"
+
+	"github.com/qeedquan/go-media/sdl"
+	"github.com/qeedquan/go-media/sdl/sdlgfx"
+)
+
+// coolingOff is how long the player has to wait before a limit
+// they loosened takes effect, tightening one is immediate.
+const coolingOff = 24 * time.Hour
+
+// Limit is a player set limit, zero means no limit.
+type Limit struct {
+	Value   int
+	Pending int
+	After   time.Time
+}
+
+// Limits are the responsible gaming limits of a profile.
+type Limits struct {
+	Loss    Limit
+	Time    Limit
+	Reality Limit
+}
+
+var (
+	lossLimits    = []int{0, 10, 20, 50, 100, 200, 500}
+	timeLimits    = []int{0, 15, 30, 60, 120}
+	realityLimits = []int{0, 15, 30, 60}
+)
+
+func (l *Limit) set(v int) {
+	if v != 0 && (l.Value == 0 || v < l.Value) {
+		l.Value = v
+		l.After = time.Time{}
+		return
+	}
+	if v == l.Value {
+		l.After = time.Time{}
+		return
+	}
+	l.Pending = v
+	l.After = time.Now().Add(coolingOff)
+}
+
+// update applies a loosened limit once its cooling-off period is over.
+func (l *Limit) update() bool {
+	if l.After.IsZero() || time.Now().Before(l.After) {
+		return false
+	}
+	l.Value = l.Pending
+	l.After = time.Time{}
+	return true
+}
+
+// next cycles through the preset values, starting from the
+// value the player asked for last.
+func (l *Limit) next(values []int) int {
+	v := l.Value
+	if !l.After.IsZero() {
+		v = l.Pending
+	}
+	for i := range values {
+		if values[i] == v {
+			return values[(i+1)%len(values)]
+		}
+	}
+	return values[0]
+}
+
+func (l *Limit) format(unit string) string {
+	s := tr("off")
+	if l.Value != 0 {
+		s = trf(unit, num(l.Value))
+	}
+	if !l.After.IsZero() {
+		p := tr("off")
+		if l.Pending != 0 {
+			p = trf(unit, num(l.Pending))
+		}
+		s += trf(" (%s from %s)", p, l.After.Format(locale.DateTime))
+	}
+	return s
+}
+
+func (p *Profile) updateLimits() {
+	l := &p.limits
+	changed := l.Loss.update()
+	changed = l.Time.update() || changed
+	changed = l.Reality.update() || changed
+	if changed {
+		p.save("limits", l)
+	}
+}
+
+// Session keeps track of how long the player has been playing
+// and how much they won or lost since choosing their profile. The
+// loss and time limits count from where they were last
+// acknowledged, so going on after one is reached re-arms it.
+type Session struct {
+	id       string
+	start    time.Time
+	net      int
+	check    time.Time
+	lossFrom int
+	timeFrom time.Time
+}
+
+var (
+	session Session
+)
+
+func (s *Session) reset() {
+	profile.updateLimits()
+	now := time.Now()
+	*s = Session{
+		id:       newSessionID(),
+		start:    now,
+		timeFrom: now,
+	}
+	s.schedule()
+}
+
+func (s *Session) schedule() {
+	s.check = time.Time{}
+	if n := profile.limits.Reality.Value; n > 0 {
+		s.check = time.Now().Add(time.Duration(n) * time.Minute)
+	}
+}
+
+// exceeded reports whether the session went past the loss or time
+// limit the player set.
+func (s *Session) exceeded() bool {
+	return s.lossExceeded() || s.timeExceeded()
+}
+
+func (s *Session) lossExceeded() bool {
+	l := profile.limits.Loss.Value
+	return l > 0 && s.lossFrom-s.net >= l
+}
+
+func (s *Session) timeExceeded() bool {
+	l := profile.limits.Time.Value
+	return l > 0 && time.Since(s.timeFrom) >= time.Duration(l)*time.Minute
+}
+
+// rearm lets the player go on after reaching a limit, which then
+// counts again from here.
+func (s *Session) rearm() {
+	if s.lossExceeded() {
+		s.lossFrom = s.net
+	}
+	if s.timeExceeded() {
+		s.timeFrom = time.Now()
+	}
+}
+
+func (s *Session) realityCheck() bool {
+	return !s.check.IsZero() && time.Now().After(s.check)
+}
+
+func (s *Session) summary() string {
+	net := num(s.net)
+	if s.net >= 0 {
+		net = "+" + net
+	}
+	return trf("You have played %d minutes, net %s credits", int(time.Since(s.start).Minutes()), net)
+}
+
+// checkLimits pops up a notice over the reels when a limit is
+// reached or a reality check is due, and reports whether spins
+// are refused until the player acknowledges it.
+func (g *Game) checkLimits() bool {
+	if session.exceeded() {
+		g.keys = false
+		g.menu = "l"
+		return true
+	}
+	if g.keys && session.realityCheck() {
+		g.keys = false
+		g.menu = "r"
+		return true
+	}
+	return false
+}
+
+// acknowledge handles the keys while a limit or reality check is
+// shown: space or spin goes on playing and end game ends the session.
+func (g *Game) acknowledge(sym sdl.Keycode) {
+	switch {
+	case bound(sym, actEndGame):
+		if g.cashOut() {
+			g.menu = "e"
+		}
+	case sym == sdl.K_SPACE, bound(sym, actSpin):
+		if g.menu == "l" {
+			session.rearm()
+		}
+		session.schedule()
+		g.keys = true
+		g.menu = "n"
+	}
+}
+
+func (g *Game) limitMenu() {
+	sdlgfx.ThickLine(screen.Renderer, 50, 250, 590, 250, 400, theme.Colors.Panel.Color)
+
+	y := 250 - 120
+	if g.menu == "l" {
+		blitText(g.font, 60, y+60, theme.Colors.PanelText.Color, tr("You have reached a limit you set for this session."))
+	} else {
+		blitText(g.font, 60, y+60, theme.Colors.PanelText.Color, tr("Reality check"))
+	}
+	blitText(g.font, 60, y+80, theme.Colors.PanelText.Color, session.summary())
+	blitText(g.font, 60, y+120, theme.Colors.PanelText.Color, trf("To keep playing press Space or %s", keyNames(actSpin)))
+	blitText(g.font, 60, y+140, theme.Colors.PanelText.Color, trf("To end the session press %s", keyNames(actEndGame)))
+}
+
+type limitSelector struct{}
+
+func (limitSelector) Choices() []string {
+	l := &profile.limits
+	return []string{
+		choice("Loss limit: %s", l.Loss.format("%s credits")),
+		choice("Time limit: %s", l.Time.format("%s min")),
+		choice("Reality check: %s", l.Reality.format("%s min")),
+		choice("Exit"),
+	}
+}
+
+func (limitSelector) Select(choice int) bool {
+	l := &profile.limits
+	switch choice {
+	case 0:
+		l.Loss.set(l.Loss.next(lossLimits))
+	case 1:
+		l.Time.set(l.Time.next(timeLimits))
+	case 2:
+		l.Reality.set(l.Reality.next(realityLimits))
+	default:
+		state = settings.Run
+		return true
+	}
+	profile.save("limits", l)
+	limits.Refresh()
+	return false
+}
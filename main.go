@@ -26,21 +26,41 @@ type Display struct {
 
 var (
 	conf struct {
-		assets     string
-		pref       string
-		fullscreen bool
-		music      bool
-		sound      bool
-		invincible bool
+		assets        string
+		pref          string
+		fullscreen    bool
+		music         bool
+		sound         bool
+		volume        int
+		musicVolume   int
+		effectsVolume int
+		mute          bool
+		speed         string
+		invincible    bool
+		theme         string
+		lang          string
+		kiosk         bool
+		coinKey       string
+		coinValue     int
+		idle          time.Duration
+		sasDevice     string
+		sasAddress    int
 	}
 
 	screen *Display
 
 	menu     *Menu
 	settings *Menu
+	profiles *Menu
+	slots    *Menu
+	replace  *Menu
+	limits   *Menu
+	operator *Menu
+	controls *Menu
 	game     *Game
+	hiscore  *HighScores
+	meterv   *MeterView
 	state    func()
-	score    int
 	fps      sdlgfx.FPSManager
 	texture  *sdl.Texture
 	surface  *sdl.Surface
@@ -51,7 +71,13 @@ func main() {
 	rand.Seed(time.Now().UnixNano())
 	log.SetFlags(0)
 	parseFlags()
+	if flag.NArg() > 0 {
+		command(flag.Args())
+		return
+	}
+	initAssets()
 	initSDL()
+	initKiosk()
 	load()
 	loop()
 }
@@ -59,22 +85,71 @@ func main() {
 func parseFlags() {
 	conf.assets = filepath.Join(sdl.GetBasePath(), "assets")
 	conf.pref = sdl.GetPrefPath("", "bfruit")
-	flag.StringVar(&conf.assets, "assets", conf.assets, "assets directory")
+	flag.StringVar(&conf.assets, "assets", conf.assets, "directory or .bfpack archive of assets overriding the built-in ones")
 	flag.StringVar(&conf.pref, "pref", conf.pref, "pref directory")
 	flag.BoolVar(&conf.fullscreen, "fullscreen", false, "fullscreen")
 	flag.BoolVar(&conf.music, "music", true, "enable music")
 	flag.BoolVar(&conf.sound, "sound", true, "enable sound")
+	flag.IntVar(&conf.volume, "volume", 100, "master volume in percent")
+	flag.IntVar(&conf.musicVolume, "musicvolume", 70, "music volume in percent")
+	flag.IntVar(&conf.effectsVolume, "effectsvolume", 100, "sound effects volume in percent")
+	flag.BoolVar(&conf.mute, "mute", false, "start with the sound muted")
 	flag.BoolVar(&conf.invincible, "invincible", false, "don't lose credit")
+	flag.StringVar(&conf.theme, "theme", defaultTheme, "theme under assets/themes")
+	flag.StringVar(&conf.lang, "lang", defaultLanguage, "language of the game, en or hu")
+	flag.StringVar(&conf.speed, "speed", "normal", "reel speed, normal, fast or turbo")
+	flag.BoolVar(&conf.kiosk, "kiosk", false, "run unattended as an arcade cabinet")
+	flag.StringVar(&conf.coinKey, "coinkey", "C", "key inserting a coin in kiosk mode")
+	flag.IntVar(&conf.coinValue, "coinvalue", 10, "credits per coin in kiosk mode")
+	flag.DurationVar(&conf.idle, "idle", 2*time.Minute, "idle time before returning to the attract loop in kiosk mode")
+	flag.StringVar(&conf.sasDevice, "sas", "", "serial device to speak SAS on")
+	flag.IntVar(&conf.sasAddress, "sasaddr", 1, "SAS address of the machine")
 	flag.Usage = usage
 	flag.Parse()
+	loadConfig()
+
+	if conf.sasAddress < 1 || conf.sasAddress > 127 {
+		log.Fatalf("SAS address %d out of range 1-127", conf.sasAddress)
+	}
+	for _, v := range []int{conf.volume, conf.musicVolume, conf.effectsVolume} {
+		if !validVolume(v) {
+			log.Fatalf("volume %d out of range 0-100", v)
+		}
+	}
+	if !validSpeed(conf.speed) {
+		log.Fatalf("unknown speed %q", conf.speed)
+	}
 }
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "BFruit %v: [options]\n", version)
+	fmt.Fprintf(os.Stderr, "BFruit %v: [options] [command]\n", version)
 	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\noptions can also be set in %s or as %s environment variables\n", configName, envName("<option>"))
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	fmt.Fprintln(os.Stderr, "  assets extract [dir]     write the built-in assets out for modding")
+	fmt.Fprintln(os.Stderr, "  assets check <dir|zip>   check the themes of an asset pack")
+	fmt.Fprintln(os.Stderr, "  audit verify [file ...]  replay the audit log and report mismatches")
+	fmt.Fprintln(os.Stderr, "  config                   print the configuration and where each value comes from")
+	fmt.Fprintln(os.Stderr, "  sas host <device> <poll>  send a SAS poll as the host and print the reply")
 	os.Exit(2)
 }
 
+func command(args []string) {
+	switch args[0] {
+	case "assets":
+		assetsCommand(args[1:])
+	case "audit":
+		auditCommand(args[1:])
+	case "config":
+		configCommand(args[1:])
+	case "sas":
+		sasCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		usage()
+	}
+}
+
 func newDisplay(w, h int, wflag sdl.WindowFlags) (*Display, error) {
 	window, renderer, err := sdl.CreateWindowAndRenderer(w, h, wflag)
 	if err != nil {
@@ -113,55 +188,75 @@ func initSDL() {
 	ck(err)
 
 	screen.SetTitle("BFruit")
-	screen.SetLogicalSize(640, 480)
+	screen.SetLogicalSize(logicalW, logicalH)
 	screen.SetDrawColor(sdlcolor.Black)
 	screen.Clear()
 	screen.Present()
-	sdl.ShowCursor(0)
+	showCursor()
 
 	fps.Init()
 	fps.SetRate(60)
 }
 
 func load() {
-	score = loadScore()
+	loadMeters()
+	loadCabinet()
+	loadControls()
+	startSAS()
+	loadProfile(activeProfile())
 	menu = newMenu(menuSelector{})
 	settings = newMenu(settingsSelector{})
+	profiles = newMenu(profileSelector{})
+	slots = newMenu(slotSelector{})
+	replace = newMenu(slotSelector{overwrite: true})
+	limits = newMenu(limitSelector{})
+	operator = newMenu(operatorSelector{})
+	controls = newMenu(controlsSelector{})
+	meterv = &MeterView{}
+	hiscore = newHighScores()
 	game = newGame()
 }
 
 func loop() {
 	state = intro
 	for {
-		state()
+		runScene()
 	}
 }
 
 func menuEvent() bool {
 	for {
-		ev := sdl.PollEvent()
+		ev := pollEvent()
 		if ev == nil {
 			break
 		}
 		switch ev := ev.(type) {
 		case sdl.QuitEvent:
-			os.Exit(0)
+			quit()
 		case sdl.KeyDownEvent:
-			switch ev.Sym {
-			case sdl.K_ESCAPE:
-				os.Exit(0)
-			case sdl.K_SPACE, sdl.K_RETURN:
+			input()
+			if attendant(ev) {
+				state = operatorLogin(menu.Run)
+				return true
+			}
+			switch {
+			case bound(ev.Sym, actBack):
+				quit()
+			case bound(ev.Sym, actConfirm):
 				state = menu.Run
 				return true
 			}
+		case Click:
+			state = menu.Run
+			return true
 		}
 	}
 	return false
 }
 
 func intro() {
-	border := loadImage("intro/border.png")
-	point := loadImage("intro/point.png")
+	border := loadImage(theme.Images.IntroBorder)
+	point := loadImage(theme.Images.IntroPoint)
 
 	for szam := 0; szam < 256; szam += 4 {
 		if menuEvent() {
@@ -186,10 +281,10 @@ func intro() {
 }
 
 func title() {
-	border := loadImage("intro/border.png")
-	point := loadImage("intro/point.png")
-	sun := loadImage("intro/sun.png")
-	font := loadFont("LiberationSans-Regular.ttf", 25)
+	border := loadImage(theme.Images.IntroBorder)
+	point := loadImage(theme.Images.IntroPoint)
+	sun := loadImage(theme.Images.IntroSun)
+	font := loadFont(theme.Fonts.Text, 25)
 
 	start := time.Now()
 	for {
@@ -322,11 +417,11 @@ func title() {
 		}
 
 		if dt > 3000*time.Millisecond {
-			blitText(font, 190, 273, sdlcolor.White, "nXBalazs")
+			blitText(font, 190, 273, theme.Colors.Text.Color, "nXBalazs")
 		}
 
 		if dt > 3500*time.Millisecond {
-			blitText(font, 280, 310, sdlcolor.White, "games")
+			blitText(font, 280, 310, theme.Colors.Text.Color, "games")
 		}
 
 		if 4000*time.Millisecond < dt && dt < 5000*time.Millisecond {
@@ -348,4 +443,7 @@ func title() {
 	}
 
 	state = menu.Run
+	if conf.kiosk {
+		state = intro
+	}
 }
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/qeedquan/go-media/sdl"
@@ -20,7 +19,6 @@ type bgSlide struct {
 type Menu struct {
 	bg       *bgSlide
 	selected int
-	showHS   bool
 
 	bsound *sdlmixer.Chunk
 
@@ -44,28 +42,54 @@ type Selector interface {
 	Select(choice int) bool
 }
 
+// Adjuster is a Selector with choices that go up and down, like a
+// volume, with the up and down arrows.
+type Adjuster interface {
+	Adjust(choice, delta int)
+}
+
 type menuSelector struct{}
 
 func (menuSelector) Choices() []string {
-	return []string{
-		"  New Game  ",
-		"  Settings  ",
-		"  High score  ",
-		"  Exit  ",
+	choices := []string{
+		choice("New Game"),
+		choice("Continue"),
+		choice("Settings"),
+		choice("High score"),
+		choice("Profiles"),
 	}
+	if !conf.kiosk {
+		choices = append(choices, choice("Exit"))
+	}
+	return choices
 }
 
 func (menuSelector) Select(choice int) bool {
 	switch choice {
 	case 0:
+		slot := freeSlot()
+		if slot < 0 {
+			notify("Every save slot is taken, choose one to overwrite")
+			state = replace.Run
+			return true
+		}
+		game.reset(slot)
 		state = game.Run
 		return true
 	case 1:
-		state = settings.Run
+		state = slots.Run
 		return true
 	case 2:
+		state = settings.Run
+		return true
+	case 3:
+		state = hiscore.Run
+		return true
+	case 4:
+		state = profiles.Run
+		return true
 	default:
-		os.Exit(0)
+		quit()
 	}
 
 	return false
@@ -74,9 +98,23 @@ func (menuSelector) Select(choice int) bool {
 type settingsSelector struct{}
 
 func (settingsSelector) Choices() []string {
+	sound := tr("on")
+	if conf.mute {
+		sound = tr("muted")
+	}
 	return []string{
-		"  Fullscreen  ",
-		"  Exit  ",
+		choice("Fullscreen"),
+		choice("Volume: %d%%", conf.volume),
+		choice("Music: %d%%", conf.musicVolume),
+		choice("Effects: %d%%", conf.effectsVolume),
+		choice("Sound: %s", sound),
+		choice("Theme: %s", theme.Name),
+		choice("Language: %s", locale.Name),
+		choice("Speed: %s", tr(conf.speed)),
+		choice("Controls"),
+		choice("Limits"),
+		choice("Operator"),
+		choice("Exit"),
 	}
 }
 
@@ -84,44 +122,121 @@ func (settingsSelector) Select(choice int) bool {
 	switch choice {
 	case 0:
 		conf.fullscreen = !conf.fullscreen
-		flags := sdl.WindowFlags(0)
-		if conf.fullscreen {
-			flags |= sdl.WINDOW_FULLSCREEN_DESKTOP
-		}
-		screen.SetFullscreen(flags)
+		setFullscreen(conf.fullscreen)
+		profile.saveSettings()
 		return false
-	case 1:
+	case 1, 2, 3:
+		cycleVolume(volumeSetting(choice))
+		profile.saveSettings()
+		settings.Refresh()
+		return false
+	case 4:
+		toggleMute()
+		return false
+	case 5:
+		setTheme(nextTheme())
+		profile.saveSettings()
+		settings.Refresh()
+		return false
+	case 6:
+		setLanguage(nextLanguage())
+		profile.saveSettings()
+		return false
+	case 7:
+		conf.speed = nextSpeed()
+		profile.saveSettings()
+		settings.Refresh()
+		return false
+	case 8:
+		state = controls.Run
+		return true
+	case 9:
+		state = limits.Run
+		return true
+	case 10:
+		state = operatorLogin(settings.Run)
+		return true
+	case 11:
 		state = menu.Run
 		return true
 	}
 	return false
 }
 
+func (settingsSelector) Adjust(choice, delta int) {
+	if v := volumeSetting(choice); v != nil {
+		adjustVolume(v, delta)
+		profile.saveSettings()
+		settings.Refresh()
+	}
+}
+
+// volumeSetting returns the volume a settings choice sets.
+func volumeSetting(choice int) *int {
+	switch choice {
+	case 1:
+		return &conf.volume
+	case 2:
+		return &conf.musicVolume
+	case 3:
+		return &conf.effectsVolume
+	}
+	return nil
+}
+
 var (
 	bgSlider = &bgSlide{}
 )
 
+func setFullscreen(fullscreen bool) {
+	flags := sdl.WindowFlags(0)
+	if fullscreen {
+		flags |= sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	screen.SetFullscreen(flags)
+}
+
+// menus returns every menu of the game.
+func menus() []*Menu {
+	return []*Menu{menu, settings, profiles, slots, replace, limits, operator, controls}
+}
+
 func newMenu(selector Selector) *Menu {
 	m := &Menu{
-		bg:        bgSlider,
-		smallFont: loadFont("LiberationSans-Regular.ttf", 15),
-		font:      loadFont("LiberationSans-Regular.ttf", 25),
-		bsound:    loadSound("sounds/CLICK10A.WAV"),
-		menuBG: []*Image{
-			loadImage("menubg/al.png"),
-			loadImage("menubg/ci.png"),
-			loadImage("menubg/he.png"),
-			loadImage("menubg/na.png"),
-			loadImage("menubg/di.png"),
-		},
-		sav:             loadImage("menubg/sav.png"),
-		highScore:       loadImage("menubg/highscore.png"),
-		background:      loadImage("menubg/menubg.png"),
-		backgroundAdded: loadImage("menubg/added.png"),
-		selector:        selector,
-		choices:         selector.Choices(),
+		bg:       bgSlider,
+		selector: selector,
+	}
+	m.loadTheme()
+
+	return m
+}
+
+func (m *Menu) loadTheme() {
+	t := theme
+	m.smallFont = loadFont(t.Fonts.Text, 15)
+	m.font = loadFont(t.Fonts.Text, 25)
+	m.bsound = loadSound(t.Sounds.Click)
+	m.menuBG = m.menuBG[:0]
+	for _, name := range t.Images.MenuSlides {
+		m.menuBG = append(m.menuBG, loadImage(name))
 	}
+	m.sav = loadImage(t.Images.MenuRow)
+	m.highScore = loadImage(t.Images.MenuHighScore)
+	m.background = loadImage(t.Images.MenuBackground)
+	m.backgroundAdded = loadImage(t.Images.MenuOverlay)
+	m.Reset()
+	m.Refresh()
+}
+
+func (m *Menu) Reset() {
+	*m.bg = bgSlide{}
+}
 
+// Refresh rebuilds the choices from the selector, for menus
+// whose entries change while the game is running.
+func (m *Menu) Refresh() {
+	m.choices = m.selector.Choices()
+	m.mid = m.mid[:0]
 	for _, s := range m.choices {
 		w, _, err := m.font.SizeUTF8(s)
 		ck(err)
@@ -129,15 +244,18 @@ func newMenu(selector Selector) *Menu {
 	}
 	m.allChoice = strings.Join(m.choices, "")
 
-	return m
-}
-
-func (m *Menu) Reset() {
-	*m.bg = bgSlide{}
+	if m.selected >= len(m.choices) {
+		m.selected = len(m.choices) - 1
+	}
 }
 
 func (m *Menu) Run() {
+	m.Refresh()
 	for {
+		if idle() {
+			state = intro
+			return
+		}
 		if m.event() {
 			return
 		}
@@ -148,35 +266,59 @@ func (m *Menu) Run() {
 
 func (m *Menu) event() bool {
 	for {
-		m.showHS = false
-		if m.selected == 2 {
-			m.showHS = true
-		}
-
-		ev := sdl.PollEvent()
+		ev := pollEvent()
 		if ev == nil {
 			break
 		}
 		switch ev := ev.(type) {
 		case sdl.QuitEvent:
-			os.Exit(0)
+			quit()
 		case sdl.KeyDownEvent:
-			switch ev.Sym {
-			case sdl.K_ESCAPE:
-				os.Exit(0)
-			case sdl.K_LEFT:
+			input()
+			if attendant(ev) {
+				state = operatorLogin(m.Run)
+				return true
+			}
+			switch {
+			case bound(ev.Sym, actBack):
+				// only the main menu quits, the others go back
+				// the way their last choice does
+				if m == menu {
+					quit()
+				} else if m.selector.Select(len(m.choices) - 1) {
+					return true
+				}
+			case ev.Sym == sdl.K_LEFT:
 				if m.selected--; m.selected < 0 {
 					m.selected = len(m.choices) - 1
 				}
-			case sdl.K_RIGHT:
+			case ev.Sym == sdl.K_RIGHT:
 				if m.selected++; m.selected >= len(m.choices) {
 					m.selected = 0
 				}
-			case sdl.K_SPACE, sdl.K_RETURN:
+			case ev.Sym == sdl.K_UP, ev.Sym == sdl.K_DOWN:
+				if a, ok := m.selector.(Adjuster); ok {
+					delta := 1
+					if ev.Sym == sdl.K_DOWN {
+						delta = -1
+					}
+					a.Adjust(m.selected, delta)
+				}
+			case bound(ev.Sym, actMute):
+				toggleMute()
+			case bound(ev.Sym, actConfirm):
 				if m.selector.Select(m.selected) {
 					return true
 				}
 			}
+		case Click:
+			if n := m.choiceAt(ev); n >= 0 {
+				playSound(m.bsound)
+				m.selected = n
+				if m.selector.Select(n) {
+					return true
+				}
+			}
 		}
 	}
 	return false
@@ -186,22 +328,25 @@ func (m *Menu) draw() {
 	screen.SetDrawColor(sdlcolor.Black)
 	screen.Clear()
 
-	bg := m.menuBG[m.bg.selected]
-	bg.SetAlphaMod(uint8(m.bg.alpha))
-	bg.Blit(0, 0)
-	m.backgroundAdded.Blit(0, 0)
+	m.drawSlide()
 	m.drawSelection()
 	m.background.Blit(0, 0)
 
-	blitText(m.smallFont, 3, 460, sdlcolor.White, fmt.Sprint("Balazs Nagy - BFruit -", version))
+	blitText(m.smallFont, 3, 460, theme.Colors.Text.Color, fmt.Sprint("Balazs Nagy - BFruit -", version, " - ", profile.name))
 
-	if m.showHS {
-		m.sav.Blit(0, 60)
-		m.sav.Blit(0, 120)
-		m.highScore.Blit(50, 60)
-		blitText(m.font, 295, 110, sdlcolor.White, fmt.Sprint(score))
-	}
+	m.tick()
+	drawNotices()
+	screen.Present()
+}
 
+func (m *Menu) drawSlide() {
+	bg := m.menuBG[m.bg.selected]
+	bg.SetAlphaMod(uint8(m.bg.alpha))
+	bg.Blit(0, 0)
+	m.backgroundAdded.Blit(0, 0)
+}
+
+func (m *Menu) tick() {
 	m.bg.counter += 4
 	if m.bg.counter < 245 {
 		m.bg.alpha += 4
@@ -215,15 +360,35 @@ func (m *Menu) draw() {
 		m.bg.counter = 0
 		m.bg.selected = (m.bg.selected + 1) % len(m.menuBG)
 	}
-
-	screen.Present()
 }
 
-func (m *Menu) drawSelection() {
+// choicesX is where the row of choices starts, centering the
+// selected one.
+func (m *Menu) choicesX() int {
 	x := 0
 	for i := 0; i <= m.selected; i++ {
 		x -= m.mid[i]
 	}
 	x += m.mid[m.selected] / 2
-	blitText(m.font, 320+x, 15, sdlcolor.White, fmt.Sprint(m.allChoice))
+	return 320 + x
+}
+
+// choiceAt returns the choice clicked, or -1 if the click missed
+// the row of choices.
+func (m *Menu) choiceAt(c Click) int {
+	if c.Y >= 60 {
+		return -1
+	}
+	x := m.choicesX()
+	for i, w := range m.mid {
+		if x <= c.X && c.X < x+w {
+			return i
+		}
+		x += w
+	}
+	return -1
+}
+
+func (m *Menu) drawSelection() {
+	blitText(m.font, m.choicesX(), 15, theme.Colors.Text.Color, fmt.Sprint(m.allChoice))
 }
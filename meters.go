@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/qeedquan/go-media/sdl"
+	"github.com/qeedquan/go-media/sdl/sdlimage/sdlcolor"
+)
+
+const metersVersion = 1
+
+// MeterSet are the accounting meters of a cabinet.
+type MeterSet struct {
+	CoinIn    int
+	CoinOut   int
+	Inserted  int
+	TicketIn  int
+	TicketOut int
+	Played    int
+	Won       int
+	Jackpots  int
+}
+
+// Meters are kept for the machine rather than a profile. The
+// lifetime meters are never reset, the session ones are reset
+// by the operator.
+type Meters struct {
+	Lifetime MeterSet
+	Session  MeterSet
+}
+
+var (
+	meters Meters
+)
+
+func metersName() string {
+	return filepath.Join(conf.pref, "meters")
+}
+
+func loadMeters() {
+	_, err := loadData(metersName(), "meters", metersVersion, &meters)
+	if err != nil && !os.IsNotExist(err) {
+		notify("meters: %v", err)
+	}
+}
+
+func saveMeters() {
+	err := saveData(metersName(), "meters", metersVersion, &meters)
+	if err != nil {
+		notify("saving meters: %v", err)
+	}
+}
+
+func (m *Meters) add(f func(s *MeterSet)) {
+	f(&m.Lifetime)
+	f(&m.Session)
+}
+
+// MeterView is the operator scene showing the meters.
+type MeterView struct{}
+
+func (v *MeterView) Run() {
+	for {
+		if v.event() {
+			return
+		}
+		v.draw()
+		sdl.Delay(1000 / 60)
+	}
+}
+
+func (v *MeterView) event() bool {
+	for {
+		ev := pollEvent()
+		if ev == nil {
+			break
+		}
+		switch ev := ev.(type) {
+		case sdl.QuitEvent:
+			quit()
+		case sdl.KeyDownEvent:
+			if bound(ev.Sym, actBack) || bound(ev.Sym, actConfirm) {
+				playSound(menu.bsound)
+				state = operator.Run
+				return true
+			}
+		case Click:
+			playSound(menu.bsound)
+			state = operator.Run
+			return true
+		}
+	}
+	return false
+}
+
+func (v *MeterView) draw() {
+	m := menu
+
+	screen.SetDrawColor(sdlcolor.Black)
+	screen.Clear()
+
+	m.drawSlide()
+	m.background.Blit(0, 0)
+	for y := 60; y < 480; y += 60 {
+		m.sav.Blit(0, y)
+	}
+
+	blitText(m.font, 50, 15, theme.Colors.Text.Color, tr("Meters"))
+
+	rows := []struct {
+		name string
+		get  func(s *MeterSet) int
+	}{
+		{"Coin in", func(s *MeterSet) int { return s.CoinIn }},
+		{"Coin out", func(s *MeterSet) int { return s.CoinOut }},
+		{"Inserted", func(s *MeterSet) int { return s.Inserted }},
+		{"Ticket in", func(s *MeterSet) int { return s.TicketIn }},
+		{"Ticket out", func(s *MeterSet) int { return s.TicketOut }},
+		{"Games played", func(s *MeterSet) int { return s.Played }},
+		{"Games won", func(s *MeterSet) int { return s.Won }},
+		{"Jackpots", func(s *MeterSet) int { return s.Jackpots }},
+	}
+
+	y := 80
+	blitText(m.smallFont, 300, y, theme.Colors.Text.Color, tr("Lifetime"))
+	blitText(m.smallFont, 450, y, theme.Colors.Text.Color, tr("Session"))
+	for _, r := range rows {
+		y += 40
+		blitText(m.font, 50, y, theme.Colors.Text.Color, tr(r.name))
+		blitText(m.font, 300, y, theme.Colors.Text.Color, num(r.get(&meters.Lifetime)))
+		blitText(m.font, 450, y, theme.Colors.Text.Color, num(r.get(&meters.Session)))
+	}
+
+	pt := findPaytable(cabinet.Paytable)
+	y += 60
+	blitText(m.smallFont, 50, y, theme.Colors.Text.Color, trf("Payout profile: %s (%.1f%%)", pt.Name, pt.rtp()*100))
+
+	m.tick()
+	drawNotices()
+	screen.Present()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/qeedquan/go-media/sdl"
+	"github.com/qeedquan/go-media/sdl/sdlgfx"
+)
+
+const noticeTime = 5 * time.Second
+
+type notice struct {
+	text  string
+	until time.Time
+}
+
+var (
+	notices []notice
+)
+
+// notify shows a message to the player on top of whatever
+// scene is running for a few seconds, in the language chosen.
+func notify(format string, args ...interface{}) {
+	text := trf(format, args...)
+	log.SetPrefix("notice: ")
+	log.Print(text)
+	notices = append(notices, notice{text, time.Now().Add(noticeTime)})
+}
+
+// nk is like ek, but tells the player as well.
+func nk(err error) {
+	if err != nil {
+		notify("%v", err)
+	}
+}
+
+func drawNotices() {
+	now := time.Now()
+	for len(notices) > 0 && now.After(notices[0].until) {
+		notices = notices[1:]
+	}
+	if len(notices) == 0 {
+		return
+	}
+
+	font := loadFont(theme.Fonts.Text, 15)
+	y := 440 - 20*(len(notices)-1)
+	mid := (y + 460) / 2
+	sdlgfx.ThickLine(screen.Renderer, 0, mid, 640, mid, 460-y+4, sdl.Color{0, 0, 0, 200})
+	for _, n := range notices {
+		blitText(font, 10, y, theme.Colors.Text.Color, n.text)
+		y += 20
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const (
+	cabinetVersion  = 1
+	defaultPassword = "0000"
+)
+
+// Cabinet are the machine settings only the operator can change,
+// along with the identity of the machine printed on vouchers and
+// the secret key they are signed with.
+type Cabinet struct {
+	Password    string
+	StartCredit int
+	Paytable    string
+	MachineID   string
+	Key         string
+}
+
+var (
+	cabinet = Cabinet{
+		Password:    hashPassword(defaultPassword),
+		StartCredit: 20,
+		Paytable:    paytables[0].Name,
+	}
+
+	startCredits = []int{10, 20, 50, 100, 200, 500}
+)
+
+func cabinetName() string {
+	return filepath.Join(conf.pref, "cabinet")
+}
+
+func loadCabinet() {
+	_, err := loadData(cabinetName(), "cabinet", cabinetVersion, &cabinet)
+	if err != nil && !os.IsNotExist(err) {
+		notify("cabinet: %v", err)
+		return
+	}
+
+	if cabinet.MachineID == "" || cabinet.Key == "" {
+		var id [4]byte
+		var key [32]byte
+		rand.Read(id[:])
+		rand.Read(key[:])
+		cabinet.MachineID = hex.EncodeToString(id[:])
+		cabinet.Key = hex.EncodeToString(key[:])
+		saveCabinet()
+	}
+}
+
+func saveCabinet() {
+	err := saveData(cabinetName(), "cabinet", cabinetVersion, &cabinet)
+	if err != nil {
+		notify("saving cabinet: %v", err)
+	}
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte("bfruit operator " + password))
+	return hex.EncodeToString(sum[:])
+}
+
+func checkPassword(password string) error {
+	if subtle.ConstantTimeCompare([]byte(hashPassword(password)), []byte(cabinet.Password)) != 1 {
+		return errors.New(tr("wrong password"))
+	}
+	return nil
+}
+
+// operatorLogin asks for the operator password before going
+// to the operator menu, back is where Escape returns to.
+func operatorLogin(back func()) func() {
+	e := newEntry("Operator password:", 16, checkPassword, operator.Run)
+	e.mask = true
+	e.back = back
+	return e.Run
+}
+
+type operatorSelector struct{}
+
+func (operatorSelector) Choices() []string {
+	pt := findPaytable(cabinet.Paytable)
+	choices := []string{
+		choice("Meters"),
+		choice("Reset session meters"),
+		choice("Start credit: %s", num(cabinet.StartCredit)),
+		choice("Payout: %s %.0f%%", pt.Name, pt.rtp()*100),
+		choice("Password"),
+	}
+	if conf.kiosk {
+		choices = append(choices, choice("Shut down"))
+	}
+	return append(choices, choice("Exit"))
+}
+
+func (operatorSelector) Select(choice int) bool {
+	switch choice {
+	case 0:
+		state = meterv.Run
+		return true
+	case 1:
+		meters.Session = MeterSet{}
+		saveMeters()
+		notify("Session meters reset")
+	case 2:
+		next := startCredits[0]
+		for i, n := range startCredits {
+			if n == cabinet.StartCredit {
+				next = startCredits[(i+1)%len(startCredits)]
+				break
+			}
+		}
+		cabinet.StartCredit = next
+		saveCabinet()
+	case 3:
+		pt := findPaytable(cabinet.Paytable)
+		for i := range paytables {
+			if &paytables[i] == pt {
+				cabinet.Paytable = paytables[(i+1)%len(paytables)].Name
+				break
+			}
+		}
+		saveCabinet()
+	case 4:
+		state = newEntry("New operator password:", 16, func(password string) error {
+			if len(password) < 4 {
+				return errors.New(tr("password must have at least 4 characters"))
+			}
+			cabinet.Password = hashPassword(password)
+			saveCabinet()
+			return nil
+		}, operator.Run).Run
+		return true
+	case 5:
+		if conf.kiosk {
+			saveMeters()
+			os.Exit(0)
+		}
+		fallthrough
+	default:
+		state = settings.Run
+		return true
+	}
+	operator.Refresh()
+	return false
+}
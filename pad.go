@@ -0,0 +1,131 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/qeedquan/go-media/sdl"
+)
+
+// Game controller buttons are turned into key presses, so they go
+// through the same action bindings as the keyboard. The D-pad presses
+// the arrow keys, the other buttons press keys of their own, outside
+// the range of SDL keycodes.
+const padKeys sdl.Keycode = 1 << 24
+
+var (
+	pads = make(map[int32]*sdl.GameController)
+
+	padNames = map[uint8]string{
+		sdl.CONTROLLER_BUTTON_A:             "A",
+		sdl.CONTROLLER_BUTTON_B:             "B",
+		sdl.CONTROLLER_BUTTON_X:             "X",
+		sdl.CONTROLLER_BUTTON_Y:             "Y",
+		sdl.CONTROLLER_BUTTON_BACK:          "Back",
+		sdl.CONTROLLER_BUTTON_START:         "Start",
+		sdl.CONTROLLER_BUTTON_LEFTSHOULDER:  "LB",
+		sdl.CONTROLLER_BUTTON_RIGHTSHOULDER: "RB",
+		sdl.CONTROLLER_BUTTON_LEFTSTICK:     "LS",
+		sdl.CONTROLLER_BUTTON_RIGHTSTICK:    "RS",
+	}
+
+	padArrows = map[uint8]sdl.Keycode{
+		sdl.CONTROLLER_BUTTON_DPAD_UP:    sdl.K_UP,
+		sdl.CONTROLLER_BUTTON_DPAD_DOWN:  sdl.K_DOWN,
+		sdl.CONTROLLER_BUTTON_DPAD_LEFT:  sdl.K_LEFT,
+		sdl.CONTROLLER_BUTTON_DPAD_RIGHT: sdl.K_RIGHT,
+	}
+)
+
+var (
+	padA     = padKey(sdl.CONTROLLER_BUTTON_A)
+	padB     = padKey(sdl.CONTROLLER_BUTTON_B)
+	padBack  = padKey(sdl.CONTROLLER_BUTTON_BACK)
+	padStart = padKey(sdl.CONTROLLER_BUTTON_START)
+)
+
+func padKey(button uint8) sdl.Keycode {
+	return padKeys | sdl.Keycode(button)
+}
+
+func isPadKey(k sdl.Keycode) bool {
+	return k&padKeys != 0
+}
+
+// pollEvent is sdl.PollEvent for the scenes. It opens and closes
+// controllers as they are plugged in and out, and hands out their
+// buttons as key presses, as well as clicks on the on-screen buttons
+// given. Other clicks and taps are handed out as a Click. What the
+// SAS host sent is applied first.
+func pollEvent(buttons ...Button) sdl.Event {
+	sasPoll()
+	for {
+		ev := sdl.PollEvent()
+		if c, ok := click(ev); ok {
+			input()
+			for _, b := range buttons {
+				if b.hit(c) {
+					return sdl.KeyDownEvent{Keysym: sdl.Keysym{Sym: bindings[b.Action][0]}}
+				}
+			}
+			return c
+		}
+
+		switch e := ev.(type) {
+		case sdl.ControllerDeviceAddedEvent:
+			openPad(int(e.Which))
+		case sdl.ControllerDeviceRemovedEvent:
+			closePad(e.Which)
+		case sdl.ControllerButtonDownEvent:
+			sym, found := padArrows[e.Button]
+			if !found {
+				sym = padKey(e.Button)
+			}
+			return sdl.KeyDownEvent{Keysym: sdl.Keysym{Sym: sym}}
+		default:
+			return ev
+		}
+	}
+}
+
+func openPad(index int) {
+	if !sdl.IsGameController(index) {
+		return
+	}
+	c, err := sdl.GameControllerOpen(index)
+	if err != nil {
+		log.SetPrefix("pad: ")
+		log.Print(err)
+		return
+	}
+	pads[int32(c.Joystick().InstanceID())] = c
+	notify("Controller connected: %s", c.Name())
+}
+
+func closePad(id int32) {
+	if c, found := pads[id]; found {
+		notify("Controller disconnected: %s", c.Name())
+		c.Close()
+		delete(pads, id)
+	}
+}
+
+// keyName is sdl.GetKeyName knowing the controller buttons.
+func keyName(k sdl.Keycode) string {
+	if isPadKey(k) {
+		return "Pad " + padNames[uint8(k&^padKeys)]
+	}
+	return sdl.GetKeyName(k)
+}
+
+func keyFromName(name string) sdl.Keycode {
+	if s := strings.TrimPrefix(name, "Pad "); s != name {
+		for button, n := range padNames {
+			if n == s {
+				return padKey(button)
+			}
+		}
+		return sdl.K_UNKNOWN
+	}
+	return sdl.GetKeyFromName(name)
+}
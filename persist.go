@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Every file kept in the pref directory starts with a header line
+//
+//	bfruit <kind> <version> <crc32> <length>
+//
+// followed by the payload. Files are replaced by writing a temporary
+// file, syncing it and renaming it over the old one, whose last good
+// copy is kept with a .bak suffix so a corrupt file can be recovered.
+const persistMagic = "bfruit"
+
+var (
+	errChecksum = errors.New("checksum mismatch")
+	errHeader   = errors.New("bad header")
+)
+
+func writeFile(name, kind string, version int, data []byte) error {
+	dir := filepath.Dir(name)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	_, err = fmt.Fprintf(f, "%s %s %d %08x %d\n", persistMagic, kind, version, crc32.ChecksumIEEE(data), len(data))
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if xerr := f.Close(); err == nil {
+		err = xerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// keep the current file as the backup, but only if it is good,
+	// otherwise a corrupt file would clobber the last good copy
+	if _, _, err := readRaw(name, kind); err == nil {
+		os.Rename(name, name+".bak")
+	}
+
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// readFile returns the payload of a file written by writeFile.
+// If the file is damaged, the backup is used instead and the
+// player is told about it.
+func readFile(name, kind string) ([]byte, int, error) {
+	data, version, err := readRaw(name, kind)
+	if err == nil || os.IsNotExist(err) && !exists(name+".bak") {
+		return data, version, err
+	}
+
+	bdata, bversion, berr := readRaw(name+".bak", kind)
+	if berr != nil {
+		return nil, 0, fmt.Errorf("%s: %v", filepath.Base(name), err)
+	}
+	notify("%s could not be read (%v), restored the last good copy", filepath.Base(name), err)
+	return bdata, bversion, nil
+}
+
+func readRaw(name, kind string) ([]byte, int, error) {
+	buf, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	i := bytes.IndexByte(buf, '\n')
+	if i < 0 {
+		return nil, 0, errHeader
+	}
+
+	var (
+		magic, hkind string
+		version, n   int
+		sum          uint32
+	)
+	_, err = fmt.Sscanf(string(buf[:i]), "%s %s %d %x %d", &magic, &hkind, &version, &sum, &n)
+	if err != nil || magic != persistMagic || hkind != kind {
+		return nil, 0, errHeader
+	}
+
+	data := buf[i+1:]
+	if len(data) != n || crc32.ChecksumIEEE(data) != sum {
+		return nil, 0, errChecksum
+	}
+	return data, version, nil
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func saveData(name, kind string, version int, v interface{}) error {
+	buf, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFile(name, kind, version, buf)
+}
+
+// loadData decodes a file written by saveData, refusing files
+// written by a newer version than maxVersion.
+func loadData(name, kind string, maxVersion int, v interface{}) (int, error) {
+	data, version, err := readFile(name, kind)
+	if err != nil {
+		return 0, err
+	}
+	if version > maxVersion {
+		return version, fmt.Errorf("%s: unsupported version %d", filepath.Base(name), version)
+	}
+	return version, json.Unmarshal(data, v)
+}
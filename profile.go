@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultProfile  = "Player"
+	maxProfileName  = 12
+	profileVersion  = 1
+	settingsVersion = 2
+)
+
+type Stats struct {
+	Games   int
+	Spins   int
+	Bet     int
+	Won     int
+	BestWin int
+}
+
+// Settings are the options the player changed away from the config
+// file while the profile was active, by option name.
+type Settings map[string]string
+
+// legacySettings names the options in settings of version 1, which
+// kept every setting whether it was changed or not.
+var legacySettings = map[string]string{
+	"Fullscreen":    "fullscreen",
+	"Music":         "music",
+	"Sound":         "sound",
+	"Volume":        "volume",
+	"MusicVolume":   "musicvolume",
+	"EffectsVolume": "effectsvolume",
+	"Mute":          "mute",
+	"Theme":         "theme",
+	"Language":      "lang",
+	"Speed":         "speed",
+}
+
+type Profile struct {
+	name     string
+	dir      string
+	scores   *ScoreTable
+	stats    Stats
+	settings Settings
+	limits   Limits
+}
+
+var (
+	profile *Profile
+)
+
+func profilesDir() string {
+	return filepath.Join(conf.pref, "profiles")
+}
+
+func listProfiles() []string {
+	var names []string
+
+	fis, err := ioutil.ReadDir(profilesDir())
+	if err != nil {
+		return names
+	}
+	for _, fi := range fis {
+		if fi.IsDir() {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func validProfileName(name string) error {
+	if name == "" {
+		return errors.New(tr("profile name is empty"))
+	}
+	if len(name) > maxProfileName {
+		return errors.New(trf("profile name is longer than %d characters", maxProfileName))
+	}
+	for _, r := range name {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == ' ', r == '_', r == '-':
+		default:
+			return errors.New(trf("profile name contains invalid character %q", r))
+		}
+	}
+	if strings.TrimSpace(name) != name {
+		return errors.New(tr("profile name has leading or trailing spaces"))
+	}
+	return nil
+}
+
+func createProfile(name string) error {
+	if err := validProfileName(name); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(profilesDir(), name)
+	if _, err := os.Stat(dir); err == nil {
+		return errors.New(trf("profile %q already exists", name))
+	}
+	return os.MkdirAll(dir, 0755)
+}
+
+func deleteProfile(name string) error {
+	if err := validProfileName(name); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(profilesDir(), name))
+}
+
+// loadProfile makes the named profile active, creating it if it
+// does not exist yet, and applies its settings.
+func loadProfile(name string) {
+	dir := filepath.Join(profilesDir(), name)
+	if _, err := os.Stat(dir); err != nil {
+		nk(createProfile(name))
+		migrateLegacy(dir)
+	}
+
+	p := &Profile{
+		name: name,
+		dir:  dir,
+	}
+	p.scores = loadScores(dir)
+	p.load("stats", &p.stats)
+	if p.loadSettings() {
+		p.writeSettings()
+	}
+	p.load("limits", &p.limits)
+	profile = p
+	session.reset()
+	recoverSpin()
+
+	nk(writeFile(filepath.Join(conf.pref, "profile"), "profile", profileVersion, []byte(name)))
+	p.apply()
+}
+
+// activeProfile returns the name of the last used profile.
+func activeProfile() string {
+	buf, _, err := readFile(filepath.Join(conf.pref, "profile"), "profile")
+	if err == nil {
+		name := strings.TrimSpace(string(buf))
+		if validProfileName(name) == nil {
+			return name
+		}
+	}
+
+	names := listProfiles()
+	if len(names) > 0 {
+		return names[0]
+	}
+	return defaultProfile
+}
+
+// migrateLegacy moves the score file of older versions, which kept
+// a single score directly in the pref directory, into a profile
+// where loadScores imports it.
+func migrateLegacy(dir string) {
+	legacy := filepath.Join(conf.pref, "score")
+	if exists(legacy) {
+		nk(os.Rename(legacy, filepath.Join(dir, "score")))
+	}
+}
+
+func (p *Profile) load(name string, v interface{}) {
+	_, err := loadData(filepath.Join(p.dir, name), name, profileVersion, v)
+	if err != nil && !os.IsNotExist(err) {
+		notify("%s: %v", name, err)
+	}
+}
+
+func (p *Profile) save(name string, v interface{}) {
+	err := saveData(filepath.Join(p.dir, name), name, profileVersion, v)
+	if err != nil {
+		notify("saving %s: %v", name, err)
+	}
+}
+
+func (p *Profile) saveStats() {
+	p.save("stats", &p.stats)
+}
+
+func (p *Profile) settingsName() string {
+	return filepath.Join(p.dir, "settings")
+}
+
+// loadSettings reads the settings of the profile. Of settings of
+// version 1 it keeps only what differs from the config file, and
+// reports they need writing again.
+func (p *Profile) loadSettings() bool {
+	p.settings = make(Settings)
+
+	var raw json.RawMessage
+	version, err := loadData(p.settingsName(), "settings", settingsVersion, &raw)
+	if err == nil && version < 2 {
+		var old map[string]interface{}
+		err = json.Unmarshal(raw, &old)
+		for field, value := range old {
+			name, found := legacySettings[field]
+			if v := fmt.Sprint(value); found && v != fileSettings[name].value {
+				p.settings[name] = v
+			}
+		}
+		if err == nil {
+			return true
+		}
+	} else if err == nil {
+		err = json.Unmarshal(raw, &p.settings)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		notify("settings: %v", err)
+	}
+	return false
+}
+
+func (p *Profile) writeSettings() {
+	err := saveData(p.settingsName(), "settings", settingsVersion, p.settings)
+	if err != nil {
+		notify("saving settings: %v", err)
+	}
+}
+
+// saveSettings saves the settings changed in the game to the
+// profile, dropping those back at their value in the config file.
+// Those given in the environment or on the command line only last
+// for the run and keep their saved value.
+func (p *Profile) saveSettings() {
+	for _, name := range settingOptions {
+		if overridden(name) {
+			continue
+		}
+		value := flag.Lookup(name).Value.String()
+		if value == fileSettings[name].value {
+			delete(p.settings, name)
+			sources[name] = fileSettings[name].source
+		} else {
+			p.settings[name] = value
+			sources[name] = fromProfile
+		}
+	}
+	p.writeSettings()
+}
+
+// apply makes the settings of the profile current over the config
+// file, except for those given in the environment or on the command
+// line.
+func (p *Profile) apply() {
+	name, code := conf.theme, conf.lang
+	for _, opt := range settingOptions {
+		if overridden(opt) {
+			continue
+		}
+		o := fileSettings[opt]
+		if v, found := p.settings[opt]; found && validSetting(opt, v) {
+			o = option{v, fromProfile}
+		}
+		switch opt {
+		case "theme":
+			name, sources[opt] = o.value, o.source
+		case "lang":
+			code, sources[opt] = o.value, o.source
+		default:
+			setOption(opt, o.value, o.source, p.settingsName())
+		}
+	}
+	setFullscreen(conf.fullscreen)
+	setVolume()
+	setTheme(name)
+	setLanguage(code)
+}
+
+func validSetting(name, value string) bool {
+	switch name {
+	case "volume", "musicvolume", "effectsvolume":
+		n, err := strconv.Atoi(value)
+		return err == nil && validVolume(n)
+	case "speed":
+		return validSpeed(value)
+	}
+	return true
+}
+
+type profileSelector struct{}
+
+func (profileSelector) Choices() []string {
+	var choices []string
+	for _, name := range listProfiles() {
+		if name == profile.name {
+			name = "[" + name + "]"
+		}
+		choices = append(choices, "  "+name+"  ")
+	}
+	return append(choices,
+		choice("New"),
+		choice("Delete"),
+		choice("Exit"),
+	)
+}
+
+func (profileSelector) Select(choice int) bool {
+	names := listProfiles()
+	switch choice -= len(names); choice {
+	case 0:
+		state = newEntry("Enter profile name:", maxProfileName, func(name string) error {
+			if err := createProfile(name); err != nil {
+				return err
+			}
+			loadProfile(name)
+			return nil
+		}, profiles.Run).Run
+		return true
+	case 1:
+		nk(deleteProfile(profile.name))
+		names = listProfiles()
+		name := defaultProfile
+		if len(names) > 0 {
+			name = names[0]
+		}
+		loadProfile(name)
+		profiles.Refresh()
+		return false
+	case 2:
+		state = menu.Run
+		return true
+	default:
+		loadProfile(names[choice+len(names)])
+		state = menu.Run
+		return true
+	}
+}
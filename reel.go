@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/qeedquan/go-media/sdl"
+	"github.com/qeedquan/go-media/sdl/sdlmixer"
+)
+
+// A reel scrolls its strip of symbols down by the pixel, speeding
+// up, running and slowing down again to stop on the new symbols,
+// where it bounces a little past them and settles. Where it is
+// follows from the time since the spin started, not from frames,
+// so it moves the same at any frame rate.
+const (
+	reelStart   = 1200 * time.Millisecond // until the first reel stops
+	reelStagger = 450 * time.Millisecond  // between the reels stopping
+	reelBounce  = 300 * time.Millisecond
+	reelSpin    = 12 // symbols the first reel scrolls, more for the others
+	reelAccel   = 0.15
+	reelDecel   = 0.3
+	reelStretch = 0.25 // how much the fastest symbols stretch
+	bounceSize  = 0.12 // of a symbol
+)
+
+// Reel is one reel rolling. The strip is top to bottom: a spare
+// symbol to bounce past, the new symbols, random ones and the old
+// symbols the reel starts on.
+type Reel struct {
+	x       int
+	strip   []*Image
+	stop    time.Duration
+	channel int
+	stopped bool
+}
+
+func (g *Game) newReel(n int) *Reel {
+	img := g.images
+	var strip []*Image
+	strip = append(strip, img[randn(0, numSymbols)])
+	for i := 0; i < 3; i++ {
+		strip = append(strip, img[g.show[n*3+i]-1])
+	}
+	for i := 0; i < reelSpin+n*reelSpin/2; i++ {
+		strip = append(strip, img[randn(0, numSymbols)])
+	}
+	for i := 0; i < 3; i++ {
+		strip = append(strip, img[g.showOld[n*3+i]-1])
+	}
+
+	speed := time.Duration(speedFactor())
+	return &Reel{
+		x:       theme.Layout.ReelX[n],
+		strip:   strip,
+		stop:    (reelStart + time.Duration(n)*reelStagger) / speed,
+		channel: playSound(g.rsound),
+	}
+}
+
+// distance is how many symbols the reel scrolls to stop.
+func (r *Reel) distance() float64 {
+	return float64(len(r.strip) - 4)
+}
+
+// motion returns how far the reel has scrolled, in symbols, and how
+// fast it goes, in symbols a second. The speed goes up and down in
+// a trapezoid, so the reel eases in and out.
+func (r *Reel) motion(t time.Duration) (pos, vel float64) {
+	d, T := r.distance(), r.stop.Seconds()
+	u := t.Seconds() / T
+	peak := 1 / (1 - reelAccel/2 - reelDecel/2)
+
+	switch {
+	case u <= 0:
+		return 0, 0
+	case u < reelAccel:
+		return d * peak * u * u / (2 * reelAccel), d * peak * u / reelAccel / T
+	case u < 1-reelDecel:
+		return d * peak * (u - reelAccel/2), d * peak / T
+	case u < 1:
+		v := 1 - u
+		return d * (1 - peak*v*v/(2*reelDecel)), d * peak * v / reelDecel / T
+	}
+
+	s := (t - r.stop).Seconds() / reelBounce.Seconds()
+	if s >= 1 {
+		return d, 0
+	}
+	return d + bounceSize*math.Sin(math.Pi*s)*(1-s), 0
+}
+
+func (r *Reel) settled(t time.Duration) bool {
+	return t >= r.stop+reelBounce
+}
+
+// draw draws the symbols in view, stretched along the way they roll
+// the faster they go.
+func (r *Reel) draw(t time.Duration) {
+	pos, vel := r.motion(t)
+	if t >= r.stop && !r.stopped {
+		sdlmixer.HaltChannel(r.channel)
+		r.stopped = true
+	}
+
+	ys := theme.Layout.ReelY
+	cell := float64(ys[1] - ys[0])
+	top := float64(len(r.strip)-3) - pos
+	peak := r.distance() / r.stop.Seconds()
+	stretch := 1 + reelStretch*math.Min(vel/peak, 1)
+
+	first := int(math.Floor(top))
+	for i := first; i <= first+3; i++ {
+		if i < 0 || i >= len(r.strip) {
+			continue
+		}
+		m := r.strip[i]
+		y := float64(ys[0]) + (float64(i)-top)*cell
+		h := float64(m.h) * stretch
+		m.BlitSize(r.x, int(y+(float64(m.h)-h)/2), m.w, int(h))
+	}
+}
+
+// reelArea is the part of the screen the reels show through.
+func reelArea() *sdl.Rect {
+	l := &theme.Layout
+	w, h := l.ReelX[1]-l.ReelX[0], l.ReelY[1]-l.ReelY[0]
+	return &sdl.Rect{
+		X: int32(l.ReelX[0]),
+		Y: int32(l.ReelY[0]),
+		W: int32(l.ReelX[2] + w - l.ReelX[0]),
+		H: int32(l.ReelY[2] + h - l.ReelY[0]),
+	}
+}
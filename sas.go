@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A subset of the slot accounting system (SAS) protocol spoken over
+// a serial port, so a floor monitoring host can read the meters and
+// credits, see games start and end, lock the machine out and
+// transfer credits to it.
+//
+// Real SAS marks the address byte of a poll with a ninth wakeup bit,
+// which a plain serial port or a pseudo-terminal can't carry, so
+// polls are told apart by their address byte alone.
+
+const (
+	sasShutdown = 0x01
+	sasStartup  = 0x02
+	sasMeters   = 0x0F
+	sasCoinIn   = 0x11
+	sasCoinOut  = 0x12
+	sasDrop     = 0x14
+	sasJackpots = 0x15
+	sasPlayed   = 0x16
+	sasWon      = 0x17
+	sasCredits  = 0x1A
+	sasBonus    = 0x8A
+
+	sasNoActivity = 0x00
+	sasGameStart  = 0x7E
+	sasGameEnd    = 0x7F
+)
+
+// What the host asked the machine to do, handed to the main
+// thread through sasEvents.
+const (
+	sasLock = iota
+	sasUnlock
+	sasTransfer
+)
+
+type sasEvent struct {
+	kind   int
+	amount int
+}
+
+// sasError is a poll the machine turns down, the link stays up.
+type sasError struct {
+	error
+}
+
+// SAS is the machine side of the link. The serial port is served
+// from its own goroutine, which only sees the meters and credits
+// the main thread published and never touches the game directly.
+type SAS struct {
+	addr byte
+	rw   io.ReadWriteCloser
+	done chan struct{}
+
+	mu         sync.Mutex
+	meters     MeterSet
+	credit     int
+	exceptions []byte
+}
+
+var (
+	sas         *SAS
+	sasEvents   = make(chan sasEvent, 16)
+	locked      bool
+	transferred int
+)
+
+func startSAS() {
+	if conf.sasDevice == "" {
+		return
+	}
+
+	log.SetPrefix("sas: ")
+	f, err := openSerial(conf.sasDevice)
+	if err != nil {
+		notify("SAS: %v", err)
+		return
+	}
+
+	sas = newSAS(byte(conf.sasAddress), f)
+	sas.meters = meters.Lifetime
+	go sas.serve()
+}
+
+func newSAS(addr byte, rw io.ReadWriteCloser) *SAS {
+	return &SAS{
+		addr: addr,
+		rw:   rw,
+		done: make(chan struct{}),
+	}
+}
+
+// sasCRC is the CRC-16 used by SAS, CCITT polynomial with the
+// bits reversed and a zero seed, sent low byte first.
+func sasCRC(b []byte) uint16 {
+	var crc uint16
+	for _, c := range b {
+		q := (crc ^ uint16(c)) & 0x0f
+		crc = (crc >> 4) ^ (q * 0x1081)
+		q = (crc ^ uint16(c>>4)) & 0x0f
+		crc = (crc >> 4) ^ (q * 0x1081)
+	}
+	return crc
+}
+
+func sasSeal(b []byte) []byte {
+	var crc [2]byte
+	binary.LittleEndian.PutUint16(crc[:], sasCRC(b))
+	return append(b, crc[:]...)
+}
+
+func sasCheck(b []byte) bool {
+	n := len(b) - 2
+	return n >= 0 && binary.LittleEndian.Uint16(b[n:]) == sasCRC(b[:n])
+}
+
+// bcd encodes v as n bytes of packed decimal, most significant first.
+func bcd(v, n int) []byte {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v%10) | byte(v/10%10)<<4
+		v /= 100
+	}
+	return b
+}
+
+func unbcd(b []byte) (int, error) {
+	v := 0
+	for _, c := range b {
+		hi, lo := int(c>>4), int(c&0xf)
+		if hi > 9 || lo > 9 {
+			return 0, fmt.Errorf("bad BCD byte %#02x", c)
+		}
+		v = v*100 + hi*10 + lo
+	}
+	return v, nil
+}
+
+// publish makes the current meters and credit visible to the host,
+// it is called from the main thread whenever they change.
+func (s *SAS) publish(m MeterSet, credit int) {
+	s.mu.Lock()
+	s.meters = m
+	s.credit = credit
+	s.mu.Unlock()
+}
+
+// exception queues an event for the host to pick up with a general poll.
+func (s *SAS) exception(code byte) {
+	s.mu.Lock()
+	if len(s.exceptions) < 32 {
+		s.exceptions = append(s.exceptions, code)
+	}
+	s.mu.Unlock()
+}
+
+// serve answers the host until the link is stopped or fails, a
+// device gone or a hung up line ends it for the run.
+func (s *SAS) serve() {
+	defer close(s.done)
+	r := bufio.NewReader(s.rw)
+	for {
+		err := s.handle(r)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, os.ErrClosed) {
+			return
+		}
+
+		log.SetPrefix("sas: ")
+		if _, ok := err.(sasError); ok {
+			log.Print(err)
+			continue
+		}
+		log.Printf("link lost: %v", err)
+		return
+	}
+}
+
+// stop closes the link and waits for serve to return.
+func (s *SAS) stop() {
+	s.rw.Close()
+	<-s.done
+}
+
+func (s *SAS) handle(r *bufio.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if b == 0x80|s.addr {
+		return s.write([]byte{s.nextException()})
+	}
+	if b != s.addr {
+		return nil
+	}
+
+	cmd, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case sasShutdown, sasStartup:
+		if _, err := s.readRest(r, b, cmd, 0); err != nil {
+			return err
+		}
+		kind := sasLock
+		if cmd == sasStartup {
+			kind = sasUnlock
+		}
+		return s.send(sasEvent{kind: kind})
+
+	case sasBonus:
+		msg, err := s.readRest(r, b, cmd, 5)
+		if err != nil {
+			return err
+		}
+		amount, err := unbcd(msg[2:6])
+		if err != nil {
+			return sasError{err}
+		}
+		return s.send(sasEvent{kind: sasTransfer, amount: amount})
+
+	case sasMeters:
+		s.mu.Lock()
+		m := s.meters
+		s.mu.Unlock()
+		resp := []byte{s.addr, cmd}
+		for _, v := range []int{0, m.CoinIn, m.CoinOut, m.Inserted, m.Jackpots, m.Played} {
+			resp = append(resp, bcd(v, 4)...)
+		}
+		return s.write(sasSeal(resp))
+
+	case sasCoinIn, sasCoinOut, sasDrop, sasJackpots, sasPlayed, sasWon, sasCredits:
+		s.mu.Lock()
+		m, v := s.meters, s.credit
+		s.mu.Unlock()
+		switch cmd {
+		case sasCoinIn:
+			v = m.CoinIn
+		case sasCoinOut:
+			v = m.CoinOut
+		case sasDrop:
+			v = m.Inserted
+		case sasJackpots:
+			v = m.Jackpots
+		case sasPlayed:
+			v = m.Played
+		case sasWon:
+			v = m.Won
+		}
+		return s.write(sasSeal(append([]byte{s.addr, cmd}, bcd(v, 4)...)))
+	}
+
+	// unsupported polls are ignored, as the protocol asks
+	return nil
+}
+
+// readRest reads the data and CRC of a long poll.
+func (s *SAS) readRest(r *bufio.Reader, addr, cmd byte, n int) ([]byte, error) {
+	msg := make([]byte, 2+n+2)
+	msg[0], msg[1] = addr, cmd
+	if _, err := io.ReadFull(r, msg[2:]); err != nil {
+		return nil, err
+	}
+	if !sasCheck(msg) {
+		return nil, sasError{fmt.Errorf("bad CRC on long poll %#02x", cmd)}
+	}
+	return msg, nil
+}
+
+// send hands a host command to the main thread and acknowledges
+// it, or leaves it unacknowledged for the host to retry if the
+// main thread is falling behind.
+func (s *SAS) send(ev sasEvent) error {
+	select {
+	case sasEvents <- ev:
+		return s.write([]byte{s.addr})
+	default:
+		return sasError{errors.New("event queue full, dropped long poll")}
+	}
+}
+
+func (s *SAS) nextException() byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.exceptions) == 0 {
+		return sasNoActivity
+	}
+	code := s.exceptions[0]
+	s.exceptions = s.exceptions[1:]
+	return code
+}
+
+func (s *SAS) write(b []byte) error {
+	_, err := s.rw.Write(b)
+	return err
+}
+
+// sasPublish and sasException are no-ops when SAS is not enabled.
+func sasPublish(credit int) {
+	if sas != nil {
+		sas.publish(meters.Lifetime, credit)
+	}
+}
+
+func sasException(code byte) {
+	if sas != nil {
+		sas.exception(code)
+	}
+}
+
+// sasPoll applies the commands the host sent since the last call.
+// It runs with every pollEvent, so the host is heard in every scene,
+// and the credits transferred wait for the game in transferred.
+func sasPoll() {
+	for {
+		select {
+		case ev := <-sasEvents:
+			switch ev.kind {
+			case sasLock:
+				locked = true
+				notify("Machine locked by the host")
+			case sasUnlock:
+				locked = false
+				notify("Machine enabled by the host")
+			case sasTransfer:
+				transferred += ev.amount
+				notify("%d credits transferred by the host", ev.amount)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// sasCommand is a minimal SAS host to exercise the machine side,
+// for example against one end of a pseudo-terminal pair made with
+//
+//	socat -d -d pty,raw,echo=0 pty,raw,echo=0
+func sasCommand(args []string) {
+	if len(args) < 3 || args[0] != "host" {
+		fmt.Fprintln(os.Stderr, "usage: bfruit sas host <device> poll|lock|unlock|credits|meters|coinin|coinout|drop|jackpots|played|won|transfer <credits>")
+		os.Exit(2)
+	}
+
+	f, err := openSerial(args[1])
+	ck(err)
+	defer f.Close()
+
+	addr := byte(conf.sasAddress)
+	var msg []byte
+	n := 0
+	switch args[2] {
+	case "poll":
+		msg, n = []byte{0x80 | addr}, 1
+	case "lock":
+		msg, n = sasSeal([]byte{addr, sasShutdown}), 1
+	case "unlock":
+		msg, n = sasSeal([]byte{addr, sasStartup}), 1
+	case "transfer":
+		if len(args) < 4 {
+			sasCommand(nil)
+		}
+		v, err := strconv.Atoi(args[3])
+		ck(err)
+		msg, n = sasSeal(append(append([]byte{addr, sasBonus}, bcd(v, 4)...), 0)), 1
+	case "meters":
+		msg, n = []byte{addr, sasMeters}, 2+6*4+2
+	default:
+		cmds := map[string]byte{
+			"credits":  sasCredits,
+			"coinin":   sasCoinIn,
+			"coinout":  sasCoinOut,
+			"drop":     sasDrop,
+			"jackpots": sasJackpots,
+			"played":   sasPlayed,
+			"won":      sasWon,
+		}
+		cmd, found := cmds[args[2]]
+		if !found {
+			sasCommand(nil)
+		}
+		msg, n = []byte{addr, cmd}, 2+4+2
+	}
+
+	_, err = f.Write(msg)
+	ck(err)
+
+	resp := make([]byte, n)
+	f.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = io.ReadFull(f, resp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "no response:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("% x\n", resp)
+
+	if n > 2 {
+		if !sasCheck(resp) {
+			fmt.Fprintln(os.Stderr, "bad CRC")
+			os.Exit(1)
+		}
+		for i := 2; i+4 <= n-2; i += 4 {
+			v, err := unbcd(resp[i : i+4])
+			ck(err)
+			fmt.Println(v)
+		}
+	}
+}
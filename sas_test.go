@@ -0,0 +1,261 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestSASCRC(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint16
+	}{
+		{"", 0},
+		{"123456789", 0x2189},
+	}
+	for _, tt := range tests {
+		if got := sasCRC([]byte(tt.in)); got != tt.want {
+			t.Errorf("sasCRC(%q) = %#04x, want %#04x", tt.in, got, tt.want)
+		}
+	}
+
+	// the CRC over a message and its own CRC comes out zero
+	msg := sasSeal([]byte{0x01, sasShutdown})
+	if !sasCheck(msg) || sasCRC(msg) != 0 {
+		t.Errorf("sealed message % x fails its CRC", msg)
+	}
+	msg[1] ^= 1
+	if sasCheck(msg) {
+		t.Errorf("corrupt message % x passes its CRC", msg)
+	}
+}
+
+func TestBCD(t *testing.T) {
+	tests := []struct {
+		v    int
+		n    int
+		want []byte
+	}{
+		{0, 4, []byte{0x00, 0x00, 0x00, 0x00}},
+		{1234, 4, []byte{0x00, 0x00, 0x12, 0x34}},
+		{98765432, 4, []byte{0x98, 0x76, 0x54, 0x32}},
+		{7, 1, []byte{0x07}},
+	}
+	for _, tt := range tests {
+		got := bcd(tt.v, tt.n)
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("bcd(%d, %d) = % x, want % x", tt.v, tt.n, got, tt.want)
+		}
+		v, err := unbcd(got)
+		if err != nil || v != tt.v {
+			t.Errorf("unbcd(% x) = %d, %v, want %d", got, v, err, tt.v)
+		}
+	}
+
+	if _, err := unbcd([]byte{0x12, 0x3a}); err == nil {
+		t.Errorf("unbcd accepted a byte that is not decimal")
+	}
+}
+
+// openPTY opens a pseudo-terminal pair, the master to act as the
+// host on and the slave set up like the machine's serial port.
+func openPTY(t *testing.T) (host, machine *os.File) {
+	host, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// Fd would put the master back in blocking mode, which the read
+	// deadlines of the host need
+	var unlock, n uint32
+	var errno syscall.Errno
+	raw, err := host.SyscallConn()
+	if err == nil {
+		err = raw.Control(func(fd uintptr) {
+			_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock)))
+			if errno == 0 {
+				_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n)))
+			}
+		})
+	}
+	if err == nil && errno != 0 {
+		err = errno
+	}
+	if err != nil {
+		host.Close()
+		t.Skip(err)
+	}
+
+	machine, err = openSerial(fmt.Sprint("/dev/pts/", n))
+	if err != nil {
+		host.Close()
+		t.Skip(err)
+	}
+	return host, machine
+}
+
+// sasHost is the host end of a link to a SAS being served.
+type sasHost struct {
+	t *testing.T
+	f *os.File
+}
+
+func (h *sasHost) send(b []byte) {
+	if _, err := h.f.Write(b); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// recv reads a response of n bytes, or fails the test if the
+// machine does not answer in time.
+func (h *sasHost) recv(n int) []byte {
+	b := make([]byte, n)
+	h.f.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.ReadFull(h.f, b); err != nil {
+		h.t.Fatalf("no response: %v", err)
+	}
+	return b
+}
+
+// silent checks the machine does not answer.
+func (h *sasHost) silent() {
+	var b [1]byte
+	h.f.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	if n, _ := h.f.Read(b[:]); n > 0 {
+		h.t.Fatalf("unexpected response % x", b[:n])
+	}
+}
+
+func (h *sasHost) event() sasEvent {
+	select {
+	case ev := <-sasEvents:
+		return ev
+	case <-time.After(2 * time.Second):
+		h.t.Fatal("no event reached the main thread")
+	}
+	return sasEvent{}
+}
+
+// quietLog keeps the polls the machine turns down out of the test
+// output.
+func quietLog(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+	})
+}
+
+// served waits for serve to return.
+func served(t *testing.T, s *SAS) {
+	select {
+	case <-s.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("serve did not return")
+	}
+}
+
+func TestSASServe(t *testing.T) {
+	hf, mf := openPTY(t)
+	defer hf.Close()
+	quietLog(t)
+	for len(sasEvents) > 0 {
+		<-sasEvents
+	}
+
+	const addr = 1
+	s := newSAS(addr, mf)
+	s.publish(MeterSet{CoinIn: 1500, CoinOut: 1320, Inserted: 200, Jackpots: 2, Played: 300, Won: 45}, 380)
+	go s.serve()
+	h := &sasHost{t: t, f: hf}
+
+	// general polls hand out the queued exceptions in order
+	s.exception(sasGameStart)
+	s.exception(sasGameEnd)
+	for _, want := range []byte{sasGameStart, sasGameEnd, sasNoActivity} {
+		h.send([]byte{0x80 | addr})
+		if got := h.recv(1)[0]; got != want {
+			t.Fatalf("general poll returned %#02x, want %#02x", got, want)
+		}
+	}
+
+	h.send([]byte{addr, sasMeters})
+	resp := h.recv(2 + 6*4 + 2)
+	if !sasCheck(resp) {
+		t.Fatalf("meters % x have a bad CRC", resp)
+	}
+	for i, want := range []int{0, 1500, 1320, 200, 2, 300} {
+		v, err := unbcd(resp[2+i*4 : 6+i*4])
+		if err != nil || v != want {
+			t.Errorf("meter %d = %d, %v, want %d", i, v, err, want)
+		}
+	}
+
+	for cmd, want := range map[byte]int{sasCredits: 380, sasWon: 45, sasDrop: 200} {
+		h.send([]byte{addr, cmd})
+		resp := h.recv(2 + 4 + 2)
+		if !sasCheck(resp) || resp[1] != cmd {
+			t.Fatalf("poll %#02x returned % x", cmd, resp)
+		}
+		if v, _ := unbcd(resp[2:6]); v != want {
+			t.Errorf("poll %#02x = %d, want %d", cmd, v, want)
+		}
+	}
+
+	// lock, unlock and transfers are acknowledged with the address
+	// and handed to the main thread
+	for _, tt := range []struct {
+		msg  []byte
+		want sasEvent
+	}{
+		{sasSeal([]byte{addr, sasShutdown}), sasEvent{kind: sasLock}},
+		{sasSeal([]byte{addr, sasStartup}), sasEvent{kind: sasUnlock}},
+		{sasSeal(append(append([]byte{addr, sasBonus}, bcd(250, 4)...), 0)), sasEvent{kind: sasTransfer, amount: 250}},
+	} {
+		h.send(tt.msg)
+		if got := h.recv(1)[0]; got != addr {
+			t.Fatalf("long poll % x acknowledged with %#02x", tt.msg, got)
+		}
+		if ev := h.event(); ev != tt.want {
+			t.Fatalf("long poll % x sent %+v, want %+v", tt.msg, ev, tt.want)
+		}
+	}
+
+	// a long poll with a bad CRC is neither acknowledged nor acted on
+	msg := sasSeal(append(append([]byte{addr, sasBonus}, bcd(900, 4)...), 0))
+	msg[len(msg)-1] ^= 0xff
+	h.send(msg)
+	h.silent()
+	if len(sasEvents) != 0 {
+		t.Fatalf("long poll with a bad CRC sent %+v", <-sasEvents)
+	}
+
+	// polls for other addresses are left alone
+	h.send([]byte{0x80 | (addr + 1)})
+	h.silent()
+
+	go s.stop()
+	served(t, s)
+}
+
+// TestSASHangUp checks the link ends when the host hangs up,
+// rather than serve reading the dead line forever.
+func TestSASHangUp(t *testing.T) {
+	hf, mf := openPTY(t)
+	defer mf.Close()
+	quietLog(t)
+
+	s := newSAS(1, mf)
+	go s.serve()
+	hf.Close()
+	served(t, s)
+}
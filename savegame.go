@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	saveSlots   = 3
+	saveVersion = 1
+)
+
+// SaveGame is a game in progress, written when the player leaves
+// the game so it can be continued later. LineBet is the bet the
+// last spin was played with, which the winning lines are shown at.
+type SaveGame struct {
+	Date    time.Time
+	Credit  int
+	Bet     int
+	LineBet int
+	LastWin int
+	Spins   int
+	Show    [9]int
+	Wins    [5]int
+	Spun    bool
+}
+
+func saveName(slot int) string {
+	return filepath.Join(profile.dir, fmt.Sprint("save", slot+1))
+}
+
+func loadSave(slot int) (*SaveGame, error) {
+	s := &SaveGame{}
+	_, err := loadData(saveName(slot), "save", saveVersion, s)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SaveGame) write(slot int) error {
+	s.Date = time.Now()
+	err := saveData(saveName(slot), "save", saveVersion, s)
+	if err != nil {
+		return fmt.Errorf("saving game: %v", err)
+	}
+	return nil
+}
+
+func removeSave(slot int) {
+	for _, name := range []string{saveName(slot), saveName(slot) + ".bak"} {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			notify("removing saved game: %v", err)
+		}
+	}
+}
+
+// freeSlot returns the first slot without a saved game,
+// or -1 if all of them are taken.
+func freeSlot() int {
+	for i := 0; i < saveSlots; i++ {
+		if !exists(saveName(i)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// slotSelector continues the game of a slot, or with overwrite
+// starts a new game in one, for when every slot is taken.
+type slotSelector struct {
+	overwrite bool
+}
+
+func (c slotSelector) Choices() []string {
+	var choices []string
+	for i := 0; i < saveSlots; i++ {
+		text := tr("empty")
+		s, err := loadSave(i)
+		switch {
+		case err == nil:
+			text = trf("%s credits, %s", num(s.Credit), s.Date.Format(locale.DateTime))
+		case !os.IsNotExist(err):
+			text = tr("damaged")
+		}
+		if c.overwrite {
+			choices = append(choices, choice("Overwrite slot %d: %s", i+1, text))
+		} else {
+			choices = append(choices, choice("Slot %d: %s", i+1, text))
+		}
+	}
+	return append(choices, choice("Exit"))
+}
+
+func (c slotSelector) Select(choice int) bool {
+	if choice >= saveSlots {
+		state = menu.Run
+		return true
+	}
+	if c.overwrite {
+		game.reset(choice)
+		state = game.Run
+		return true
+	}
+
+	s, err := loadSave(choice)
+	switch {
+	case err == nil:
+		game.restore(choice, s)
+	case os.IsNotExist(err):
+		game.reset(choice)
+	default:
+		notify("%v", err)
+		return false
+	}
+	state = game.Run
+	return true
+}
@@ -2,53 +2,103 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 )
 
-func loadScore() int {
-	var err error
+const (
+	scoreVersion = 1
+	maxScores    = 10
+)
 
-	defer func() {
-		if err != nil {
-			log.SetPrefix("score: ")
-			log.Println(err)
-		}
-	}()
+type ScoreEntry struct {
+	Name  string
+	Score int
+	Date  time.Time
+	Spins int
+}
+
+// ScoreTable is the ranked high score list of a profile, highest
+// score first.
+type ScoreTable struct {
+	Entries []ScoreEntry
+}
+
+func loadScores(dir string) *ScoreTable {
+	t := &ScoreTable{}
+	_, err := loadData(filepath.Join(dir, "scores"), "scores", scoreVersion, t)
+	if os.IsNotExist(err) {
+		t.migrate(dir)
+		return t
+	}
+	if err != nil {
+		notify("high scores: %v", err)
+		return &ScoreTable{}
+	}
+	t.sort()
+	return t
+}
 
-	filename := filepath.Join(conf.pref, "score")
-	f, err := os.Open(filename)
+// migrate imports the single score kept by older versions.
+func (t *ScoreTable) migrate(dir string) {
+	f, err := os.Open(filepath.Join(dir, "score"))
 	if err != nil {
-		return 1
+		return
 	}
 	defer f.Close()
 
-	score := 1
+	score := 0
 	fmt.Fscan(f, &score)
-	return score
+	if score > 0 {
+		t.Entries = append(t.Entries, ScoreEntry{Name: "???", Score: score})
+	}
 }
 
-func saveScore(score int) {
-	var err error
-
-	defer func() {
-		if err != nil {
-			log.SetPrefix("score: ")
-			log.Println(err)
-		}
-	}()
-
-	filename := filepath.Join(conf.pref, "score")
-	f, err := os.Create(filename)
+func (t *ScoreTable) save(dir string) error {
+	err := saveData(filepath.Join(dir, "scores"), "scores", scoreVersion, t)
 	if err != nil {
-		return
+		return fmt.Errorf("saving high scores: %v", err)
 	}
+	return nil
+}
 
-	_, err = fmt.Fprint(f, score)
-	errClose := f.Close()
+func (t *ScoreTable) sort() {
+	sort.SliceStable(t.Entries, func(i, j int) bool {
+		return t.Entries[i].Score > t.Entries[j].Score
+	})
+	if len(t.Entries) > maxScores {
+		t.Entries = t.Entries[:maxScores]
+	}
+}
 
-	if err == nil {
-		err = errClose
+// Best returns the top score, or zero if the table is empty.
+func (t *ScoreTable) Best() int {
+	if len(t.Entries) == 0 {
+		return 0
 	}
+	return t.Entries[0].Score
+}
+
+// Rank returns the position a score would take in the table,
+// or -1 if it does not make it in.
+func (t *ScoreTable) Rank(score int) int {
+	if score <= 0 {
+		return -1
+	}
+	for i, e := range t.Entries {
+		if score > e.Score {
+			return i
+		}
+	}
+	if len(t.Entries) < maxScores {
+		return len(t.Entries)
+	}
+	return -1
+}
+
+func (t *ScoreTable) Insert(e ScoreEntry) {
+	t.Entries = append(t.Entries, e)
+	t.sort()
 }
@@ -0,0 +1,60 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// cbaud masks the baud rate bits of the control flags,
+// syscall does not define it.
+const cbaud = 0010017
+
+// openSerial opens a serial device in raw mode at 19200 baud,
+// eight data bits, no parity and one stop bit, like a SAS link.
+func openSerial(name string) (*os.File, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var t syscall.Termios
+	err = ioctl(f, syscall.TCGETS, &t)
+	if err == nil {
+		t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+		t.Oflag &^= syscall.OPOST
+		t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+		t.Cflag &^= syscall.CSIZE | syscall.PARENB | syscall.CSTOPB | cbaud
+		t.Cflag |= syscall.CS8 | syscall.CREAD | syscall.CLOCAL | syscall.B19200
+		t.Ispeed = syscall.B19200
+		t.Ospeed = syscall.B19200
+		t.Cc[syscall.VMIN] = 1
+		t.Cc[syscall.VTIME] = 0
+		err = ioctl(f, syscall.TCSETS, &t)
+	}
+	if err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "configure", Path: name, Err: err}
+	}
+	return f, nil
+}
+
+// ioctl goes through the raw connection, as Fd would put the file
+// in blocking mode and closing it would no longer stop a read.
+func ioctl(f *os.File, req uintptr, t *syscall.Termios) error {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var errno syscall.Errno
+	err = raw.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(t)))
+	})
+	if err == nil && errno != 0 {
+		err = errno
+	}
+	return err
+}
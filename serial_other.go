@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// openSerial opens a serial device as is, the line settings are
+// left to the system on platforms other than Linux.
+func openSerial(name string) (*os.File, error) {
+	return os.OpenFile(name, os.O_RDWR|syscall.O_NOCTTY, 0)
+}
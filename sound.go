@@ -2,11 +2,12 @@ package main
 
 import (
 	"log"
-	"path/filepath"
 
 	"github.com/qeedquan/go-media/sdl/sdlmixer"
 )
 
+const volumeStep = 10
+
 var (
 	musics = make(map[string]*sdlmixer.Music)
 	sounds = make(map[string]*sdlmixer.Chunk)
@@ -14,38 +15,59 @@ var (
 
 func loadMusic(name string) *sdlmixer.Music {
 	log.SetPrefix("sound: ")
-	filename := filepath.Join(conf.assets, name)
-	if music, found := musics[filename]; found {
+	if music, found := musics[name]; found {
 		return music
 	}
 
-	music, err := sdlmixer.LoadMUS(filename)
+	rw, err := openAsset(name, true)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	music, err := sdlmixer.LoadMUSRW(rw, true)
 	if err != nil {
 		log.Println(err)
 		return nil
 	}
 
-	musics[filename] = music
+	musics[name] = music
 	return music
 }
 
 func loadSound(name string) *sdlmixer.Chunk {
 	log.SetPrefix("sound: ")
-	filename := filepath.Join(conf.assets, name)
-	if chunk, found := sounds[filename]; found {
+	if chunk, found := sounds[name]; found {
 		return chunk
 	}
 
-	chunk, err := sdlmixer.LoadWAV(filename)
+	rw, err := openAsset(name, false)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+
+	chunk, err := sdlmixer.LoadWAVRW(rw, true)
 	if err != nil {
 		log.Print(err)
 		return nil
 	}
 
-	sounds[filename] = chunk
+	sounds[name] = chunk
 	return chunk
 }
 
+func purgeSounds() {
+	for name, music := range musics {
+		music.Free()
+		delete(musics, name)
+	}
+	for name, chunk := range sounds {
+		chunk.Free()
+		delete(sounds, name)
+	}
+}
+
 func playSound(chunk *sdlmixer.Chunk) int {
 	if !conf.sound || chunk == nil {
 		return 0
@@ -64,3 +86,57 @@ func playMusic(mus *sdlmixer.Music) {
 func stopMusic() {
 	sdlmixer.HaltMusic()
 }
+
+// setVolume sets the mixer volumes from the master, music and
+// effects volumes, which are percentages.
+func setVolume() {
+	master := conf.volume
+	if conf.mute {
+		master = 0
+	}
+	mix := func(volume int) int {
+		return sdlmixer.MAX_VOLUME * master * volume / (100 * 100)
+	}
+	sdlmixer.Volume(-1, mix(conf.effectsVolume))
+	sdlmixer.VolumeMusic(mix(conf.musicVolume))
+}
+
+// adjustVolume moves a volume up or down a step.
+func adjustVolume(volume *int, delta int) {
+	*volume += delta * volumeStep
+	if *volume < 0 {
+		*volume = 0
+	}
+	if *volume > 100 {
+		*volume = 100
+	}
+	setVolume()
+}
+
+// cycleVolume moves a volume up a step, or back to silent from full.
+func cycleVolume(volume *int) {
+	if *volume >= 100 {
+		*volume = 0
+		setVolume()
+	} else {
+		adjustVolume(volume, 1)
+	}
+}
+
+func toggleMute() {
+	conf.mute = !conf.mute
+	setVolume()
+	profile.saveSettings()
+	if settings != nil {
+		settings.Refresh()
+	}
+	if conf.mute {
+		notify("Sound muted")
+	} else {
+		notify("Sound on")
+	}
+}
+
+func validVolume(volume int) bool {
+	return 0 <= volume && volume <= 100
+}
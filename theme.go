@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/qeedquan/go-media/sdl"
+)
+
+const defaultTheme = "classic"
+
+// Theme is the manifest of a theme pack, kept as theme.json in a
+// directory under assets/themes. The paths it lists are relative to
+// the assets, so a theme can reuse the art of another one
+// and only bring the files it changes.
+type Theme struct {
+	Name    string
+	Symbols [numSymbols]string
+
+	Images struct {
+		Background     string
+		Reels          string
+		Window         string
+		MenuSlides     []string
+		MenuOverlay    string
+		MenuBackground string
+		MenuRow        string
+		MenuHighScore  string
+		IntroBorder    string
+		IntroPoint     string
+		IntroSun       string
+	}
+
+	Fonts struct {
+		Digital string
+		Text    string
+	}
+
+	Sounds struct {
+		Click string
+		Reel  string
+		Beep  string
+		Music string
+	}
+
+	Colors struct {
+		Label     Color
+		Digits    Color
+		DigitsOff Color
+		Lines     Color
+		Panel     Color
+		PanelText Color
+		Text      Color
+	}
+
+	Layout struct {
+		ReelX     [3]int
+		ReelY     [3]int
+		ReelLayer [2]int
+		Display   [2]int
+		Side      [2]int
+		Lines     [5][4]int
+	}
+}
+
+// Color is a color written as "#rrggbb" or "#rrggbbaa" in a manifest.
+type Color struct {
+	sdl.Color
+}
+
+func (c *Color) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	h := strings.TrimPrefix(s, "#")
+	if len(h) == 6 {
+		h += "ff"
+	}
+	v, err := strconv.ParseUint(h, 16, 32)
+	if len(h) != 8 || err != nil {
+		return fmt.Errorf("bad color %q", s)
+	}
+	c.Color = sdl.Color{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}
+	return nil
+}
+
+var (
+	theme *Theme
+)
+
+func themeManifest(name string) string {
+	return path.Join("themes", name, "theme.json")
+}
+
+// listThemes returns the names of the themes, built in or
+// installed in the assets directory.
+func listThemes() []string {
+	var names []string
+	for _, name := range vfs.readDir("themes") {
+		if _, err := readAsset(themeManifest(name)); err == nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func loadTheme(name string) (*Theme, error) {
+	buf, err := readAsset(themeManifest(name))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Theme{}
+	if err := json.Unmarshal(buf, t); err != nil {
+		return nil, fmt.Errorf("theme %s: %v", name, err)
+	}
+	if len(t.Images.MenuSlides) == 0 {
+		return nil, fmt.Errorf("theme %s: no menu slides", name)
+	}
+	return t, nil
+}
+
+// setTheme switches to the named theme, falling back to the default
+// one if it can't be loaded. The asset caches are emptied and the
+// scenes already made pick up the art of the new theme.
+func setTheme(name string) {
+	if theme != nil && conf.theme == name {
+		return
+	}
+
+	t, err := loadTheme(name)
+	if err != nil && name != defaultTheme {
+		notify("%v", err)
+		name = defaultTheme
+		t, err = loadTheme(name)
+	}
+	ck(err)
+
+	conf.theme = name
+	theme = t
+	if menu == nil {
+		return
+	}
+
+	stopMusic()
+	purgeImages()
+	purgeFonts()
+	purgeSounds()
+	pinned = make(map[string][]byte)
+	for _, m := range menus() {
+		m.loadTheme()
+	}
+	hiscore.loadTheme()
+	game.loadTheme()
+}
+
+// nextTheme returns the theme after the current one.
+func nextTheme() string {
+	names := listThemes()
+	for i, name := range names {
+		if name == conf.theme {
+			return names[(i+1)%len(names)]
+		}
+	}
+	if len(names) > 0 {
+		return names[0]
+	}
+	return defaultTheme
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/qeedquan/go-media/sdl"
+	"github.com/qeedquan/go-media/sdl/sdlgfx"
+	"github.com/qeedquan/go-media/sdl/sdlttf"
+)
+
+// The screen is laid out at a logical size of 640x480 and scaled to
+// the window, keeping its aspect by adding bars at the sides.
+const (
+	logicalW = 640
+	logicalH = 480
+)
+
+// Click is a left mouse button press or a tap on a touch screen,
+// at logical screen coordinates.
+type Click struct {
+	X, Y int
+}
+
+// Button is an on-screen button pressing the first key bound to
+// its action.
+type Button struct {
+	Rect   sdl.Rect
+	Label  string
+	Action Action
+}
+
+func (b *Button) hit(c Click) bool {
+	r := b.Rect
+	return int32(c.X) >= r.X && int32(c.X) < r.X+r.W &&
+		int32(c.Y) >= r.Y && int32(c.Y) < r.Y+r.H
+}
+
+func (b *Button) draw(font *sdlttf.Font) {
+	r := b.Rect
+	c := &theme.Colors
+	x, y := int(r.X), int(r.Y)
+	w, h := int(r.W), int(r.H)
+	sdlgfx.Box(screen.Renderer, x, y, x+w-1, y+h-1, c.Panel.Color)
+	sdlgfx.ThickLine(screen.Renderer, x, y+h-1, x+w-1, y+h-1, 2, c.DigitsOff.Color)
+
+	label := tr(b.Label)
+	tw, th, err := font.SizeUTF8(label)
+	ck(err)
+	blitText(font, x+(w-tw)/2, y+(h-th)/2, c.PanelText.Color, label)
+}
+
+// click turns a mouse or touch event into a click. SDL already
+// scales mouse coordinates to the logical size, but fingers are
+// reported relative to the whole window, bars included. The mouse
+// events SDL makes up from touches are dropped, so a tap counts once.
+func click(ev sdl.Event) (Click, bool) {
+	switch ev := ev.(type) {
+	case sdl.MouseButtonDownEvent:
+		if ev.Button == sdl.BUTTON_LEFT && ev.Which != sdl.TOUCH_MOUSEID {
+			return Click{int(ev.X), int(ev.Y)}, true
+		}
+	case sdl.FingerDownEvent:
+		w, h := screen.Window.Size()
+		scale := float32(w) / logicalW
+		if s := float32(h) / logicalH; s < scale {
+			scale = s
+		}
+		if scale <= 0 {
+			break
+		}
+		x := (ev.X*float32(w) - (float32(w)-logicalW*scale)/2) / scale
+		y := (ev.Y*float32(h) - (float32(h)-logicalH*scale)/2) / scale
+		if 0 <= x && x < logicalW && 0 <= y && y < logicalH {
+			return Click{int(x), int(y)}, true
+		}
+	}
+	return Click{}, false
+}
+
+// showCursor shows the mouse pointer, except on a kiosk where the
+// screen is touched instead.
+func showCursor() {
+	if conf.kiosk {
+		sdl.ShowCursor(0)
+	} else {
+		sdl.ShowCursor(1)
+	}
+}
@@ -13,6 +13,9 @@ import (
 
 func ck(err error) {
 	if err != nil {
+		if conf.kiosk {
+			log.Panic(err)
+		}
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	vouchersVersion  = 2
+	validationDigits = 18
+)
+
+// Voucher is a cash-out ticket. The MACs are keyed with a secret of
+// the machine, so a voucher whose number, amount or date was edited
+// in the ledger no longer verifies, and neither does one that was
+// marked unredeemed again.
+type Voucher struct {
+	Number     string
+	Amount     int
+	Time       time.Time
+	Machine    string
+	MAC        string
+	Redeemed   bool
+	RedeemedAt time.Time
+	RedeemMAC  string
+}
+
+// Ledger are the vouchers the machine issued.
+type Ledger struct {
+	Vouchers []Voucher
+}
+
+func ledgerName() string {
+	return filepath.Join(conf.pref, "vouchers")
+}
+
+func loadLedger() (*Ledger, error) {
+	l := &Ledger{}
+	version, err := loadData(ledgerName(), "vouchers", vouchersVersion, l)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("voucher ledger: %v", err)
+	}
+
+	// ledgers of version 1 did not seal the redemption, take it
+	// as it was for the vouchers that verify
+	if err == nil && version < 2 {
+		for i := range l.Vouchers {
+			if v := &l.Vouchers[i]; hmac.Equal([]byte(v.sum()), []byte(v.MAC)) {
+				v.RedeemMAC = v.redeemSum()
+			}
+		}
+		if err := l.save(); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (l *Ledger) save() error {
+	err := saveData(ledgerName(), "vouchers", vouchersVersion, l)
+	if err != nil {
+		return fmt.Errorf("saving voucher ledger: %v", err)
+	}
+	return nil
+}
+
+func (v *Voucher) sum() string {
+	key, _ := hex.DecodeString(cabinet.Key)
+	h := hmac.New(sha256.New, key)
+	fmt.Fprintf(h, "%s|%d|%s|%s", v.Number, v.Amount, v.Time.UTC().Format(time.RFC3339Nano), v.Machine)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (v *Voucher) redeemSum() string {
+	key, _ := hex.DecodeString(cabinet.Key)
+	h := hmac.New(sha256.New, key)
+	fmt.Fprintf(h, "%s|%t|%s", v.Number, v.Redeemed, v.RedeemedAt.UTC().Format(time.RFC3339Nano))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (v *Voucher) valid() bool {
+	return hmac.Equal([]byte(v.sum()), []byte(v.MAC)) &&
+		hmac.Equal([]byte(v.redeemSum()), []byte(v.RedeemMAC))
+}
+
+func validationNumber() (string, error) {
+	var b strings.Builder
+	for i := 0; i < validationDigits; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(byte('0' + n.Int64()))
+	}
+	return b.String(), nil
+}
+
+// formatValidation groups a validation number the way it is
+// printed on a voucher.
+func formatValidation(number string) string {
+	var parts []string
+	for i := 0; i < len(number); i += 6 {
+		j := i + 6
+		if j > len(number) {
+			j = len(number)
+		}
+		parts = append(parts, number[i:j])
+	}
+	return strings.Join(parts, "-")
+}
+
+// issueVoucher records a voucher in the ledger and prints it.
+func issueVoucher(amount int) (*Voucher, error) {
+	l, err := loadLedger()
+	if err != nil {
+		return nil, err
+	}
+
+	number, err := validationNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	v := Voucher{
+		Number:  number,
+		Amount:  amount,
+		Time:    time.Now(),
+		Machine: cabinet.MachineID,
+	}
+	v.MAC = v.sum()
+	v.RedeemMAC = v.redeemSum()
+
+	l.Vouchers = append(l.Vouchers, v)
+	if err := l.save(); err != nil {
+		return nil, err
+	}
+	return &v, v.print()
+}
+
+func (v *Voucher) print() error {
+	dir := filepath.Join(conf.pref, "tickets")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf(`BFRUIT CASH VOUCHER
+
+Validation: %s
+Amount:     %d credits
+Date:       %s
+Machine:    %s
+Check:      %s
+
+Valid for one redemption only.
+`, formatValidation(v.Number), v.Amount, v.Time.Format("2006-01-02 15:04:05"), v.Machine, v.MAC[:16])
+
+	return ioutil.WriteFile(filepath.Join(dir, v.Number+".txt"), []byte(text), 0644)
+}
+
+// redeemVoucher marks an unredeemed voucher of the ledger as
+// redeemed and returns its amount. The ledger is saved before
+// the credits are handed out, so a voucher can't be used twice.
+func redeemVoucher(number string) (int, error) {
+	number = strings.Map(func(r rune) rune {
+		if '0' <= r && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+
+	l, err := loadLedger()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range l.Vouchers {
+		v := &l.Vouchers[i]
+		if v.Number != number {
+			continue
+		}
+		if !v.valid() {
+			return 0, errors.New(tr("voucher failed verification"))
+		}
+		if v.Redeemed {
+			return 0, errors.New(trf("voucher was already redeemed on %s", v.RedeemedAt.Format(locale.DateTime)))
+		}
+
+		v.Redeemed = true
+		v.RedeemedAt = time.Now()
+		v.RedeemMAC = v.redeemSum()
+		if err := l.save(); err != nil {
+			return 0, err
+		}
+		return v.Amount, nil
+	}
+	return 0, errors.New(tr("unknown voucher"))
+}
+
+// cashOut ends the game by printing a voucher for the credit left,
+// it reports false and leaves the credit be if none could be issued.
+func (g *Game) cashOut() bool {
+	if g.credit <= 0 {
+		g.cashed = 0
+		return true
+	}
+
+	v, err := issueVoucher(g.credit)
+	if v == nil {
+		notify("Could not print voucher: %v", err)
+		return false
+	}
+	// the credit is on the voucher now, a crash must not find it
+	// in the save as well
+	removeSave(g.slot)
+	nk(err)
+
+	g.cashed = g.credit
+	g.voucher = v
+	g.credit = 0
+	g.bet = 0
+	meters.add(func(s *MeterSet) {
+		s.TicketOut += v.Amount
+	})
+	saveMeters()
+	sasPublish(g.credit)
+	return true
+}
+
+// ticketIn asks for the validation number of a voucher and puts
+// its amount on the credit meter.
+func (g *Game) ticketIn() func() {
+	return newEntry("Validation number:", validationDigits, func(number string) error {
+		n, err := redeemVoucher(number)
+		if err != nil {
+			return err
+		}
+		g.addCredit(n, func(s *MeterSet) {
+			s.TicketIn += n
+		})
+		notify("Voucher redeemed: %d credits", n)
+		return nil
+	}, g.Run).Run
+}